@@ -0,0 +1,32 @@
+package nset
+
+import "github.com/bloeys/nset/internal/access"
+
+//storageReader is implemented by all NSet types, so the functions given to package access don't need to know T
+type storageReader interface {
+	bucketStorageUnits(bucketIndex int) []StorageType
+	storageUnitCount() uint32
+}
+
+func init() {
+
+	access.StorageUnits = func(n any, bucketIndex int) any {
+		return n.(storageReader).bucketStorageUnits(bucketIndex)
+	}
+
+	access.StorageUnitCount = func(n any) uint32 {
+		return n.(storageReader).storageUnitCount()
+	}
+}
+
+func (n *NSet[T]) bucketStorageUnits(bucketIndex int) []StorageType {
+
+	n.lazyInit()
+	return n.Buckets[bucketIndex].Data
+}
+
+func (n *NSet[T]) storageUnitCount() uint32 {
+
+	n.lazyInit()
+	return n.StorageUnitCount
+}
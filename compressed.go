@@ -0,0 +1,398 @@
+package nset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	compressedMagic   = "NSCZ"
+	compressedVersion = 1
+)
+
+//Encodings of a bucket in the compressed format
+const (
+	//compressedEmpty buckets have no elements and nothing after the encoding byte
+	compressedEmpty byte = iota
+	//compressedRaw buckets have their storage units as is
+	compressedRaw
+	//compressedDeltas buckets have a sorted list of their elements, each written as the difference from the previous one
+	compressedDeltas
+	//compressedRuns buckets have runs of equal storage units, which is good for dense buckets
+	compressedRuns
+)
+
+//The compressed format of NSet is (all integers are little endian, and uvarints are as in encoding/binary):
+//
+//	magic "NSCZ" | version (1 byte) | bits in T (1 byte) | bucket indexing bits (1 byte) | reserved zero (1 byte)
+//	for each bucket: encoding (1 byte) | storage unit count (uvarint) | encoded bucket
+//
+//where the encoded bucket is one of:
+//
+//	compressedEmpty: nothing (and no storage unit count)
+//	compressedRaw: the storage units (uint64 each)
+//	compressedDeltas: element count (uvarint) | first element, then the difference from the previous element (uvarint each)
+//	compressedRuns: run count (uvarint) | for each run: empty storage units before the run (uvarint) |
+//		storage units in the run (uvarint) | storage unit repeated in the run (uint64)
+//
+//Elements in compressedDeltas are positions inside the bucket (i.e. without the bucket bits). Empty storage units
+//at the end of a bucket aren't written, and each bucket uses the encoding that makes it smallest.
+
+//WriteCompressed writes the set in a compressed format that keeps the bucket layout of the set.
+//Each bucket is written either as raw storage units, as a list of differences between elements (good for sparse buckets),
+//or as runs of equal storage units (good for dense buckets), whichever is smallest.
+func (n *NSet[T]) WriteCompressed(w io.Writer) error {
+
+	n.lazyInit()
+
+	bw := bufio.NewWriter(w)
+	header := make([]byte, binaryHeaderSize)
+	copy(header, compressedMagic)
+	header[4] = compressedVersion
+	header[5] = typeBitsOf[T]()
+	header[6] = n.BucketIndexingBits()
+	bw.Write(header)
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(x uint64) {
+		size := binary.PutUvarint(buf, x)
+		bw.Write(buf[:size])
+	}
+
+	writeUint64 := func(x uint64) {
+		binary.LittleEndian.PutUint64(buf, x)
+		bw.Write(buf[:8])
+	}
+
+	for i := 0; i < len(n.Buckets); i++ {
+
+		data := n.Buckets[i].Data[:n.Buckets[i].usedStorageUnitCount()]
+		if len(data) == 0 {
+			bw.WriteByte(compressedEmpty)
+			continue
+		}
+
+		encoding := compressedRaw
+		size := len(data) * 8
+
+		//Each element takes at least a byte as a delta, so dense buckets don't need to check the size of their deltas
+		elementCount := storageUnitsOnesCount(data)
+		if elementCount < size {
+			if deltasSize := compressedDeltasSize(data, elementCount); deltasSize < size {
+				encoding = compressedDeltas
+				size = deltasSize
+			}
+		}
+
+		runs := storageUnitRuns(data)
+		if compressedRunsSize(runs) < size {
+			encoding = compressedRuns
+		}
+
+		bw.WriteByte(encoding)
+		writeUvarint(uint64(len(data)))
+
+		switch encoding {
+		case compressedRaw:
+
+			for j := 0; j < len(data); j++ {
+				writeUint64(uint64(data[j]))
+			}
+
+		case compressedDeltas:
+
+			writeUvarint(uint64(elementCount))
+
+			prev := uint64(0)
+			forEachStorageUnitsElement(data, func(x uint64) {
+				writeUvarint(x - prev)
+				prev = x
+			})
+
+		case compressedRuns:
+
+			writeUvarint(uint64(len(runs)))
+
+			prevEnd := 0
+			for _, run := range runs {
+				writeUvarint(uint64(run.start - prevEnd))
+				writeUvarint(uint64(run.end - run.start))
+				writeUint64(uint64(data[run.start]))
+				prevEnd = run.end
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+//ReadCompressed replaces the contents and bucket layout of the set with the ones written by WriteCompressed.
+//The set is only changed if the whole set is read without errors. Data after the set isn't read from r,
+//unless r doesn't implement io.ByteReader, in which case it is buffered and some of it might be read.
+func (n *NSet[T]) ReadCompressed(r io.Reader) error {
+
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	cr := compressedReader{r: br}
+
+	header := make([]byte, binaryHeaderSize)
+	for i := 0; i < len(header) && cr.err == nil; i++ {
+		header[i] = cr.readByte()
+	}
+
+	if cr.err != nil {
+		return cr.err
+	}
+
+	if string(header[:4]) != compressedMagic {
+		return fmt.Errorf("%w: missing compressed header", ErrInvalidBinaryData)
+	}
+
+	if header[4] != compressedVersion {
+		return fmt.Errorf("%w: unsupported compressed version %d", ErrInvalidBinaryData, header[4])
+	}
+
+	typeBits := typeBitsOf[T]()
+	if header[5] != typeBits {
+		return fmt.Errorf("%w: data is for a %d-bit type but the set is %d-bit", ErrInvalidBinaryData, header[5], typeBits)
+	}
+
+	bucketIndexingBits := header[6]
+	if bucketIndexingBits > MaxBucketIndexingBits || bucketIndexingBits > typeBits {
+		return fmt.Errorf("%w: invalid bucket indexing bits %d", ErrInvalidBinaryData, bucketIndexingBits)
+	}
+
+	maxStorageUnitCount := maxBucketStorageUnitCount(typeBits, bucketIndexingBits)
+
+	newSet := &NSet[T]{}
+	newSet.initLayout(bucketIndexingBits)
+	for i := 0; i < len(newSet.Buckets) && cr.err == nil; i++ {
+
+		encoding := cr.readByte()
+		if encoding == compressedEmpty || cr.err != nil {
+			continue
+		}
+
+		storageUnitCount := cr.readUvarint(maxStorageUnitCount)
+		if cr.err != nil {
+			break
+		}
+
+		b := &newSet.Buckets[i]
+		b.Data = make([]StorageType, storageUnitCount)
+		b.StorageUnitCount = uint32(storageUnitCount)
+		newSet.StorageUnitCount += b.StorageUnitCount
+
+		switch encoding {
+		case compressedRaw:
+
+			for j := 0; j < len(b.Data) && cr.err == nil; j++ {
+				b.Data[j] = StorageType(cr.readUint64())
+			}
+
+		case compressedDeltas:
+
+			bitCount := storageUnitCount * StorageTypeBits
+			elementCount := cr.readUvarint(bitCount)
+
+			x := uint64(0)
+			for j := uint64(0); j < elementCount && cr.err == nil; j++ {
+
+				delta := cr.readUvarint(bitCount - 1)
+				if j > 0 && delta == 0 {
+					cr.fail("repeated element")
+				}
+
+				x += delta
+				if x >= bitCount {
+					cr.fail("element is out of range")
+				}
+
+				if cr.err == nil {
+					b.Data[x/StorageTypeBits] |= 1 << (x % StorageTypeBits)
+				}
+			}
+
+		case compressedRuns:
+
+			runCount := cr.readUvarint(storageUnitCount)
+
+			start := uint64(0)
+			for j := uint64(0); j < runCount && cr.err == nil; j++ {
+
+				start += cr.readUvarint(storageUnitCount)
+				runLength := cr.readUvarint(storageUnitCount)
+				storageUnit := StorageType(cr.readUint64())
+				if start+runLength > storageUnitCount {
+					cr.fail("run is out of range")
+					break
+				}
+
+				for k := start; k < start+runLength; k++ {
+					b.Data[k] = storageUnit
+				}
+
+				start += runLength
+			}
+
+		default:
+			cr.fail(fmt.Sprintf("unknown bucket encoding %d", encoding))
+		}
+	}
+
+	if cr.err != nil {
+		return cr.err
+	}
+
+	*n = *newSet
+	return nil
+}
+
+//compressedReader reads the parts of the compressed format, and keeps the first error so that
+//reads can be checked once after a group of them
+type compressedReader struct {
+	r   io.ByteReader
+	err error
+}
+
+func (c *compressedReader) fail(reason string) {
+	if c.err == nil {
+		c.err = fmt.Errorf("%w: %s", ErrInvalidBinaryData, reason)
+	}
+}
+
+func (c *compressedReader) setReadErr(err error) {
+
+	if c.err != nil {
+		return
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.fail("data ended early")
+		return
+	}
+
+	c.err = err
+}
+
+func (c *compressedReader) readByte() byte {
+
+	if c.err != nil {
+		return 0
+	}
+
+	x, err := c.r.ReadByte()
+	c.setReadErr(err)
+	return x
+}
+
+//readUvarint reads a uvarint and fails if it is bigger than max
+func (c *compressedReader) readUvarint(max uint64) uint64 {
+
+	if c.err != nil {
+		return 0
+	}
+
+	x, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		c.setReadErr(err)
+		return 0
+	}
+
+	if x > max {
+		c.fail(fmt.Sprintf("value %d is bigger than the allowed %d", x, max))
+		return 0
+	}
+
+	return x
+}
+
+func (c *compressedReader) readUint64() uint64 {
+
+	x := uint64(0)
+	for i := 0; i < 8; i++ {
+		x |= uint64(c.readByte()) << (i * 8)
+	}
+
+	return x
+}
+
+//uvarintSize returns the number of bytes used by x as a uvarint
+func uvarintSize(x uint64) int {
+	return (bits.Len64(x|1) + 6) / 7
+}
+
+//forEachStorageUnitsElement calls f in ascending order with the position of every set bit in data
+func forEachStorageUnitsElement(data []StorageType, f func(x uint64)) {
+
+	for j := 0; j < len(data); j++ {
+
+		storageUnit := data[j]
+		for storageUnit != 0 {
+			f(uint64(j*StorageTypeBits + bits.TrailingZeros64(uint64(storageUnit))))
+			storageUnit &= storageUnit - 1
+		}
+	}
+}
+
+//compressedDeltasSize returns the size of data in the compressedDeltas encoding, without the storage unit count
+func compressedDeltasSize(data []StorageType, elementCount int) int {
+
+	size := uvarintSize(uint64(elementCount))
+
+	prev := uint64(0)
+	forEachStorageUnitsElement(data, func(x uint64) {
+		size += uvarintSize(x - prev)
+		prev = x
+	})
+
+	return size
+}
+
+//storageUnitRun is the non-empty storage units [start, end) that are all equal
+type storageUnitRun struct {
+	start, end int
+}
+
+//storageUnitRuns returns the runs of equal non-empty storage units in data
+func storageUnitRuns(data []StorageType) []storageUnitRun {
+
+	runs := make([]storageUnitRun, 0)
+	for j := 0; j < len(data); {
+
+		if data[j] == 0 {
+			j++
+			continue
+		}
+
+		run := storageUnitRun{start: j, end: j + 1}
+		for run.end < len(data) && data[run.end] == data[j] {
+			run.end++
+		}
+
+		runs = append(runs, run)
+		j = run.end
+	}
+
+	return runs
+}
+
+//compressedRunsSize returns the size of data in the compressedRuns encoding with the given runs, without the storage unit count
+func compressedRunsSize(runs []storageUnitRun) int {
+
+	size := uvarintSize(uint64(len(runs)))
+
+	prevEnd := 0
+	for _, run := range runs {
+		size += uvarintSize(uint64(run.start-prevEnd)) + uvarintSize(uint64(run.end-run.start)) + 8
+		prevEnd = run.end
+	}
+
+	return size
+}
@@ -0,0 +1,172 @@
+package nset_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestNSetCompressed(t *testing.T) {
+
+	sparse := nset.NewNSet[uint32]()
+	for x := uint32(0); x < 10_000_000; x += 997 {
+		sparse.Add(x)
+	}
+
+	dense := nset.FromRange[uint32](0, 10_000_000)
+	dense.Remove(5_000_000)
+
+	mixed := sparse.Copy()
+	mixed.Union(nset.FromRange[uint32](1<<31, 1<<31+1_000_000))
+	mixed.AddMany(math.MaxUint32, 1<<30)
+
+	small := nset.NewNSet[uint8](nset.WithBucketIndexingBits(3))
+	small.AddMany(0, 1, 2, 100, 255)
+
+	sets := []*nset.NSet[uint32]{nset.NewNSet[uint32](), sparse, dense, mixed}
+	for _, n := range sets {
+
+		buf := &bytes.Buffer{}
+		AllTrue(t, n.WriteCompressed(buf) == nil)
+
+		binaryData, err := n.MarshalBinary()
+		AllTrue(t, err == nil, buf.Len() <= len(binaryData))
+
+		n2 := &nset.NSet[uint32]{}
+		AllTrue(t, n2.ReadCompressed(buf) == nil, n2.IsEq(n), buf.Len() == 0)
+		IsEq(t, n.BucketIndexingBits(), n2.BucketIndexingBits())
+	}
+
+	//Sparse sets are stored as deltas and dense ones as runs, both much smaller than raw storage units
+	for _, n := range []*nset.NSet[uint32]{sparse, dense} {
+
+		buf := &bytes.Buffer{}
+		AllTrue(t, n.WriteCompressed(buf) == nil)
+
+		binaryData, err := n.MarshalBinary()
+		AllTrue(t, err == nil, buf.Len() < len(binaryData)/4)
+	}
+
+	//Small types and readers that aren't byte readers
+	buf := &bytes.Buffer{}
+	AllTrue(t, small.WriteCompressed(buf) == nil)
+
+	small2 := nset.NewNSet[uint8]()
+	AllTrue(t, small2.ReadCompressed(struct{ io.Reader }{buf}) == nil, small2.IsEq(small))
+	IsEq(t, uint8(3), small2.BucketIndexingBits())
+
+	//Many sets in one stream
+	buf.Reset()
+	AllTrue(t, sparse.WriteCompressed(buf) == nil, dense.WriteCompressed(buf) == nil)
+
+	n1, n2 := &nset.NSet[uint32]{}, &nset.NSet[uint32]{}
+	AllTrue(t, n1.ReadCompressed(buf) == nil, n2.ReadCompressed(buf) == nil)
+	AllTrue(t, n1.IsEq(sparse), n2.IsEq(dense))
+}
+
+func TestNSetCompressedInvalid(t *testing.T) {
+
+	n := nset.FromSlice([]uint16{1, 2, 3, 1000, 1001, 1002, math.MaxUint16})
+	n.Union(nset.FromRange[uint16](20_000, 30_000))
+
+	buf := &bytes.Buffer{}
+	AllTrue(t, n.WriteCompressed(buf) == nil)
+	data := buf.Bytes()
+
+	//The set isn't changed by data that is cut short
+	target := nset.FromSlice([]uint16{7})
+	for i := 0; i < len(data); i++ {
+		AllTrue(t, errors.Is(target.ReadCompressed(bytes.NewReader(data[:i])), nset.ErrInvalidBinaryData))
+		AllTrue(t, target.Len() == 1, target.Contains(7))
+	}
+
+	AllTrue(t, target.ReadCompressed(bytes.NewReader(data)) == nil, target.IsEq(n))
+
+	//Wrong type
+	AllTrue(t, errors.Is(nset.NewNSet[uint32]().ReadCompressed(bytes.NewReader(data)), nset.ErrInvalidBinaryData))
+
+	//Values out of range
+	bad := append([]byte{}, data[:8]...)
+	bad = append(bad, 2, 1, 1, 64)
+	AllTrue(t, errors.Is(target.ReadCompressed(bytes.NewReader(bad)), nset.ErrInvalidBinaryData))
+}
+
+//compressedBenchSets are the sets from the random and dense benchmark data
+func compressedBenchSets() map[string]*nset.NSet[uint32] {
+
+	rand.Seed(RandSeed)
+	random := nset.NewNSet[uint32]()
+	for i := 0; i < 100_000; i++ {
+		random.Add(rand.Uint32() % maxBenchSize)
+	}
+
+	randomDense := nset.NewNSet[uint32]()
+	for i := 0; i < maxBenchSize; i++ {
+		randomDense.Add(rand.Uint32() % maxBenchSize)
+	}
+
+	return map[string]*nset.NSet[uint32]{
+		"Rand":      random,
+		"RandDense": randomDense,
+		"Dense":     nset.FromRange[uint32](0, maxBenchSize-1),
+		"Sorted":    nset.FromSlice(sortedBenchValues()),
+	}
+}
+
+func BenchmarkNSetWriteCompressed(b *testing.B) {
+
+	for name, n := range compressedBenchSets() {
+		b.Run(name, func(b *testing.B) {
+
+			binaryData, _ := n.MarshalBinary()
+
+			buf := &bytes.Buffer{}
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				n.WriteCompressed(buf)
+			}
+
+			b.ReportMetric(float64(buf.Len()), "bytes")
+			b.ReportMetric(float64(len(binaryData)), "binary-bytes")
+		})
+	}
+}
+
+func BenchmarkNSetReadCompressed(b *testing.B) {
+
+	for name, n := range compressedBenchSets() {
+		b.Run(name, func(b *testing.B) {
+
+			buf := &bytes.Buffer{}
+			n.WriteCompressed(buf)
+			data := buf.Bytes()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n2 := &nset.NSet[uint32]{}
+				n2.ReadCompressed(bytes.NewReader(data))
+			}
+		})
+	}
+}
+
+func BenchmarkNSetUnmarshalBinary(b *testing.B) {
+
+	for name, n := range compressedBenchSets() {
+		b.Run(name, func(b *testing.B) {
+
+			data, _ := n.MarshalBinary()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n2 := &nset.NSet[uint32]{}
+				n2.UnmarshalBinary(data)
+			}
+		})
+	}
+}
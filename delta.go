@@ -0,0 +1,274 @@
+package nset
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = Delta{}
+	_ encoding.BinaryUnmarshaler = &Delta{}
+)
+
+//ErrDeltaMismatch is returned (wrapped) by Apply when the delta was made for sets with a different element type or bucket layout
+var ErrDeltaMismatch = errors.New("nset: delta doesn't match set")
+
+const (
+	deltaBinaryMagic   = "NSDL"
+	deltaBinaryVersion = 1
+)
+
+//DeltaWord is the new value of a single storage unit
+type DeltaWord struct {
+	Bucket           BucketType
+	StorageUnitIndex uint32
+	StorageUnit      StorageType
+}
+
+//Delta is the difference between two sets with the same element type and bucket layout, as returned by Diff.
+//It holds the storage units that changed, so its size depends on how many storage units changed and not on the size of the sets.
+type Delta struct {
+	typeBits           uint8
+	bucketIndexingBits uint8
+	//Words are the changed storage units, sorted by bucket and then by storage unit index
+	Words []DeltaWord
+}
+
+//Diff returns the changes that turn oldSet into newSet when passed to Apply. The delta uses the layout of newSet,
+//so if oldSet has a different layout it is converted first.
+func Diff[T IntsIf](oldSet, newSet *NSet[T]) Delta {
+
+	oldSet.lazyInit()
+	newSet.lazyInit()
+
+	if !oldSet.hasSameLayout(newSet) {
+		converted := newSet.newEmptyWithSameLayout()
+		oldSet.ForEach(func(x T) bool {
+			converted.Add(x)
+			return true
+		})
+		oldSet = converted
+	}
+
+	d := Delta{
+		typeBits:           typeBitsOf[T](),
+		bucketIndexingBits: newSet.BucketIndexingBits(),
+		Words:              make([]DeltaWord, 0),
+	}
+
+	for i := 0; i < len(newSet.Buckets); i++ {
+
+		b1 := &oldSet.Buckets[i]
+		b2 := &newSet.Buckets[i]
+
+		storageUnitCount := len(b1.Data)
+		if len(b2.Data) > storageUnitCount {
+			storageUnitCount = len(b2.Data)
+		}
+
+		for j := 0; j < storageUnitCount; j++ {
+
+			//Storage units that aren't allocated are empty
+			var oldStorageUnit, newStorageUnit StorageType
+			if j < len(b1.Data) {
+				oldStorageUnit = b1.Data[j]
+			}
+
+			if j < len(b2.Data) {
+				newStorageUnit = b2.Data[j]
+			}
+
+			if oldStorageUnit != newStorageUnit {
+				d.Words = append(d.Words, DeltaWord{Bucket: BucketType(i), StorageUnitIndex: uint32(j), StorageUnit: newStorageUnit})
+			}
+		}
+	}
+
+	return d
+}
+
+//Apply sets the storage units changed in d to their new values. Applying Diff(a, b) to a set equal to a makes it equal to b,
+//and applying a delta more than once is the same as applying it once.
+//
+//The set must have the same element type and bucket layout as the sets d was made from.
+func (n *NSet[T]) Apply(d Delta) error {
+
+	n.lazyInit()
+
+	if d.typeBits != typeBitsOf[T]() || d.bucketIndexingBits != n.BucketIndexingBits() {
+		return fmt.Errorf("%w: delta is for a %d-bit type with %d bucket indexing bits, but the set is a %d-bit type with %d",
+			ErrDeltaMismatch, d.typeBits, d.bucketIndexingBits, typeBitsOf[T](), n.BucketIndexingBits())
+	}
+
+	//Validate first so that a bad delta doesn't leave the set half changed
+	maxStorageUnitCount := maxBucketStorageUnitCount(d.typeBits, d.bucketIndexingBits)
+	for _, w := range d.Words {
+		if int(w.Bucket) >= len(n.Buckets) || uint64(w.StorageUnitIndex) >= maxStorageUnitCount {
+			return fmt.Errorf("%w: storage unit %d of bucket %d is out of range", ErrDeltaMismatch, w.StorageUnitIndex, w.Bucket)
+		}
+	}
+
+	for _, w := range d.Words {
+
+		b := &n.Buckets[w.Bucket]
+		if w.StorageUnitIndex >= b.StorageUnitCount {
+
+			//Not allocating is the same as setting to zero
+			if w.StorageUnit == 0 {
+				continue
+			}
+
+			n.growToFit(b, w.StorageUnitIndex)
+		}
+
+		n.setStorageUnit(w.Bucket, w.StorageUnitIndex, w.StorageUnit)
+	}
+
+	return nil
+}
+
+//The binary format of Delta is (all integers are little endian):
+//
+//	magic "NSDL" | version (1 byte) | bits in T (1 byte) | bucket indexing bits (1 byte) | reserved zero (1 byte)
+//	word count (uvarint)
+//	for each word: bucket minus previous bucket (uvarint) | storage unit index minus previous index if in the same bucket,
+//	otherwise the index (uvarint) | storage unit (uint64)
+//
+//Changes tend to be clustered, so the deltas between positions are mostly one byte.
+
+//MarshalBinary encodes the delta in a compact binary format
+func (d Delta) MarshalBinary() ([]byte, error) {
+
+	data := make([]byte, binaryHeaderSize, binaryHeaderSize+binary.MaxVarintLen64+len(d.Words)*10)
+	copy(data, deltaBinaryMagic)
+	data[4] = deltaBinaryVersion
+	data[5] = d.typeBits
+	data[6] = d.bucketIndexingBits
+
+	varintBuf := make([]byte, binary.MaxVarintLen64)
+	appendUvarint := func(x uint64) {
+		size := binary.PutUvarint(varintBuf, x)
+		data = append(data, varintBuf[:size]...)
+	}
+
+	appendUvarint(uint64(len(d.Words)))
+
+	prevBucket := BucketType(0)
+	prevIndex := uint32(0)
+	for i, w := range d.Words {
+
+		if i > 0 && (w.Bucket < prevBucket || (w.Bucket == prevBucket && w.StorageUnitIndex <= prevIndex)) {
+			return nil, fmt.Errorf("nset: delta words are not sorted by bucket and storage unit index")
+		}
+
+		indexDelta := w.StorageUnitIndex
+		if i > 0 && w.Bucket == prevBucket {
+			indexDelta -= prevIndex
+		}
+
+		appendUvarint(uint64(w.Bucket - prevBucket))
+		appendUvarint(uint64(indexDelta))
+
+		binary.LittleEndian.PutUint64(varintBuf, uint64(w.StorageUnit))
+		data = append(data, varintBuf[:8]...)
+
+		prevBucket = w.Bucket
+		prevIndex = w.StorageUnitIndex
+	}
+
+	return data, nil
+}
+
+//UnmarshalBinary replaces the delta with the one encoded in data by MarshalBinary
+func (d *Delta) UnmarshalBinary(data []byte) error {
+
+	if len(data) < binaryHeaderSize || string(data[:4]) != deltaBinaryMagic {
+		return fmt.Errorf("%w: missing delta header", ErrInvalidBinaryData)
+	}
+
+	if data[4] != deltaBinaryVersion {
+		return fmt.Errorf("%w: unsupported delta version %d", ErrInvalidBinaryData, data[4])
+	}
+
+	typeBits := data[5]
+	bucketIndexingBits := data[6]
+	if (typeBits != 8 && typeBits != 16 && typeBits != 32) || bucketIndexingBits > MaxBucketIndexingBits || bucketIndexingBits > typeBits {
+		return fmt.Errorf("%w: invalid delta layout", ErrInvalidBinaryData)
+	}
+
+	data = data[binaryHeaderSize:]
+	readUvarint := func() (uint64, error) {
+
+		x, size := binary.Uvarint(data)
+		if size <= 0 {
+			return 0, fmt.Errorf("%w: bad or missing varint", ErrInvalidBinaryData)
+		}
+
+		data = data[size:]
+		return x, nil
+	}
+
+	wordCount, err := readUvarint()
+	if err != nil {
+		return err
+	}
+
+	//Each word needs at least 10 bytes, which also stops us from allocating too much for bad data
+	if wordCount > uint64(len(data)/10) {
+		return fmt.Errorf("%w: delta is too short for %d words", ErrInvalidBinaryData, wordCount)
+	}
+
+	bucketCount := uint64(1) << bucketIndexingBits
+	maxStorageUnitCount := maxBucketStorageUnitCount(typeBits, bucketIndexingBits)
+
+	words := make([]DeltaWord, wordCount)
+	bucket := uint64(0)
+	index := uint64(0)
+	for i := 0; i < len(words); i++ {
+
+		bucketDelta, err := readUvarint()
+		if err != nil {
+			return err
+		}
+
+		indexDelta, err := readUvarint()
+		if err != nil {
+			return err
+		}
+
+		if len(data) < 8 {
+			return fmt.Errorf("%w: delta ended early", ErrInvalidBinaryData)
+		}
+
+		if bucketDelta >= bucketCount || indexDelta >= maxStorageUnitCount {
+			return fmt.Errorf("%w: delta word is out of range", ErrInvalidBinaryData)
+		}
+
+		if i == 0 || bucketDelta > 0 {
+			index = indexDelta
+		} else if indexDelta == 0 {
+			return fmt.Errorf("%w: repeated delta word", ErrInvalidBinaryData)
+		} else {
+			index += indexDelta
+		}
+
+		bucket += bucketDelta
+		if bucket >= bucketCount || index >= maxStorageUnitCount {
+			return fmt.Errorf("%w: delta word is out of range", ErrInvalidBinaryData)
+		}
+
+		words[i] = DeltaWord{Bucket: BucketType(bucket), StorageUnitIndex: uint32(index), StorageUnit: StorageType(binary.LittleEndian.Uint64(data))}
+		data = data[8:]
+	}
+
+	if len(data) != 0 {
+		return fmt.Errorf("%w: %d extra bytes after delta", ErrInvalidBinaryData, len(data))
+	}
+
+	d.typeBits = typeBits
+	d.bucketIndexingBits = bucketIndexingBits
+	d.Words = words
+	return nil
+}
@@ -0,0 +1,91 @@
+package nset_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestDelta(t *testing.T) {
+
+	oldSet := nset.FromRange[uint32](0, 100_000)
+	oldSet.AddMany(1<<30, math.MaxUint32)
+
+	newSet := oldSet.Copy()
+	newSet.Remove(5)
+	newSet.Remove(math.MaxUint32)
+	newSet.AddMany(200_000, 1<<31, 1<<31+1)
+
+	d := nset.Diff(oldSet, newSet)
+	IsEq(t, 4, len(d.Words))
+
+	//Applying
+	patched := oldSet.Copy()
+	AllTrue(t, patched.Apply(d) == nil, patched.IsEq(newSet))
+
+	//Applying twice changes nothing
+	AllTrue(t, patched.Apply(d) == nil, patched.IsEq(newSet))
+
+	//Encoding
+	data, err := d.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	var d2 nset.Delta
+	AllTrue(t, d2.UnmarshalBinary(data) == nil)
+	IsEq(t, len(d.Words), len(d2.Words))
+	for i := 0; i < len(d.Words); i++ {
+		IsEq(t, d.Words[i], d2.Words[i])
+	}
+
+	patched = oldSet.Copy()
+	AllTrue(t, patched.Apply(d2) == nil, patched.IsEq(newSet))
+
+	//No changes
+	empty := nset.Diff(newSet, newSet.Copy())
+	IsEq(t, 0, len(empty.Words))
+	data, err = empty.MarshalBinary()
+	AllTrue(t, err == nil)
+	AllTrue(t, d2.UnmarshalBinary(data) == nil, len(d2.Words) == 0)
+
+	//Old set with a different layout
+	oldOtherLayout := nset.NewNSet[uint32](nset.WithBucketIndexingBits(3))
+	oldOtherLayout.Union(oldSet)
+	d = nset.Diff(oldOtherLayout, newSet)
+	IsEq(t, 4, len(d.Words))
+
+	patched = oldSet.Copy()
+	AllTrue(t, patched.Apply(d) == nil, patched.IsEq(newSet))
+
+	//Set with a different type or layout
+	AllTrue(t, errors.Is(oldOtherLayout.Apply(d), nset.ErrDeltaMismatch))
+	AllTrue(t, errors.Is(nset.NewNSet[uint16]().Apply(nset.Diff(nset.NewNSet[uint32](), nset.NewNSet[uint32]())), nset.ErrDeltaMismatch))
+	AllTrue(t, errors.Is(nset.NewNSet[uint32]().Apply(nset.Delta{}), nset.ErrDeltaMismatch))
+
+	//Invalid data
+	data, err = nset.Diff(oldSet, newSet).MarshalBinary()
+	AllTrue(t, err == nil)
+	AllTrue(t, errors.Is(d2.UnmarshalBinary(data[:len(data)-1]), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(d2.UnmarshalBinary(append(data, 0)), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(d2.UnmarshalBinary(data[:4]), nset.ErrInvalidBinaryData))
+}
+
+func TestDeltaSize(t *testing.T) {
+
+	oldSet := nset.FromRange[uint32](0, 10_000_000)
+	newSet := oldSet.Copy()
+	for x := uint32(0); x < 10_000_000; x += 10_000 {
+		newSet.Remove(x)
+	}
+
+	d := nset.Diff(oldSet, newSet)
+	data, err := d.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	full, err := newSet.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	//1000 changed storage units, each taking 8 bytes plus a few bytes for its position
+	AllTrue(t, len(data) < 1000*12, len(data) < len(full)/100)
+}
@@ -0,0 +1,175 @@
+package nset
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = &NSet[uint8]{}
+	_ encoding.BinaryUnmarshaler = &NSet[uint8]{}
+	_ gob.GobEncoder             = &NSet[uint8]{}
+	_ gob.GobDecoder             = &NSet[uint8]{}
+)
+
+//ErrInvalidBinaryData is returned (wrapped) when decoding data that isn't a valid encoding of an NSet of the requested type
+var ErrInvalidBinaryData = errors.New("nset: invalid binary data")
+
+const (
+	binaryMagic   = "NSET"
+	binaryVersion = 1
+	//binaryHeaderSize is the size of the magic, version, type bits, bucket indexing bits and a reserved byte
+	binaryHeaderSize = 8
+)
+
+//The binary format of NSet is (all integers are little endian):
+//
+//	magic "NSET" | version (1 byte) | bits in T (1 byte) | bucket indexing bits (1 byte) | reserved zero (1 byte)
+//	storage unit count of each bucket (uint32 per bucket)
+//	zero padding so storage units start at a multiple of 8 bytes
+//	storage units of all buckets (uint64 each), starting with the first bucket
+//
+//Empty storage units at the end of a bucket are not written. Storage units are 8 byte aligned so that
+//the data can be used in place when it is itself aligned (e.g. a memory mapped file).
+
+//binaryStorageUnitsOffset returns where storage units start in the binary format of a set with bucketCount buckets
+func binaryStorageUnitsOffset(bucketCount int) int {
+	return (binaryHeaderSize + bucketCount*4 + 7) &^ 7
+}
+
+//maxBucketStorageUnitCount returns the biggest number of storage units a bucket can need, which is when it
+//has the biggest value it can hold
+func maxBucketStorageUnitCount(typeBits, bucketIndexingBits uint8) uint64 {
+	return uint64((^uint64(0)>>(64-(typeBits-bucketIndexingBits)))/StorageTypeBits) + 1
+}
+
+//usedStorageUnitCount returns the number of storage units in the bucket without the empty ones at the end
+func (b *Bucket) usedStorageUnitCount() uint32 {
+
+	count := len(b.Data)
+	for count > 0 && b.Data[count-1] == 0 {
+		count--
+	}
+
+	return uint32(count)
+}
+
+//MarshalBinary encodes the set in a compact binary format that keeps the bucket layout of the set
+func (n *NSet[T]) MarshalBinary() ([]byte, error) {
+
+	n.lazyInit()
+	storageUnitsOffset := binaryStorageUnitsOffset(len(n.Buckets))
+
+	size := storageUnitsOffset
+	for i := 0; i < len(n.Buckets); i++ {
+		size += int(n.Buckets[i].usedStorageUnitCount()) * 8
+	}
+
+	data := make([]byte, size)
+	copy(data, binaryMagic)
+	data[4] = binaryVersion
+	data[5] = typeBitsOf[T]()
+	data[6] = uint8(n.bucketIndexingBits())
+
+	offset := storageUnitsOffset
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		usedCount := b.usedStorageUnitCount()
+		binary.LittleEndian.PutUint32(data[binaryHeaderSize+i*4:], usedCount)
+
+		for j := uint32(0); j < usedCount; j++ {
+			binary.LittleEndian.PutUint64(data[offset:], uint64(b.Data[j]))
+			offset += 8
+		}
+	}
+
+	return data, nil
+}
+
+//UnmarshalBinary replaces the contents and bucket layout of the set with the ones encoded in data by MarshalBinary.
+//The encoded set must have the same element type as this set.
+func (n *NSet[T]) UnmarshalBinary(data []byte) error {
+
+	bucketIndexingBits, err := parseBinaryHeader[T](data)
+	if err != nil {
+		return err
+	}
+
+	n.initLayout(bucketIndexingBits)
+
+	offset := binaryStorageUnitsOffset(len(n.Buckets))
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		b.StorageUnitCount = binary.LittleEndian.Uint32(data[binaryHeaderSize+i*4:])
+		b.Data = make([]StorageType, b.StorageUnitCount)
+		n.StorageUnitCount += b.StorageUnitCount
+
+		for j := 0; j < len(b.Data); j++ {
+			b.Data[j] = StorageType(binary.LittleEndian.Uint64(data[offset:]))
+			offset += 8
+		}
+	}
+
+	return nil
+}
+
+//parseBinaryHeader validates the header and bucket sizes of data, and returns its bucket indexing bits
+func parseBinaryHeader[T IntsIf](data []byte) (bucketIndexingBits uint8, err error) {
+
+	if len(data) < binaryHeaderSize || string(data[:4]) != binaryMagic {
+		return 0, fmt.Errorf("%w: missing header", ErrInvalidBinaryData)
+	}
+
+	if data[4] != binaryVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidBinaryData, data[4])
+	}
+
+	typeBits := typeBitsOf[T]()
+	if data[5] != typeBits {
+		return 0, fmt.Errorf("%w: data is for a %d-bit type but the set is %d-bit", ErrInvalidBinaryData, data[5], typeBits)
+	}
+
+	bucketIndexingBits = data[6]
+	if bucketIndexingBits > MaxBucketIndexingBits || bucketIndexingBits > typeBits {
+		return 0, fmt.Errorf("%w: invalid bucket indexing bits %d", ErrInvalidBinaryData, bucketIndexingBits)
+	}
+
+	bucketCount := 1 << bucketIndexingBits
+	storageUnitsOffset := binaryStorageUnitsOffset(bucketCount)
+	if len(data) < storageUnitsOffset {
+		return 0, fmt.Errorf("%w: data is too short", ErrInvalidBinaryData)
+	}
+
+	maxBucketStorageUnitCount := maxBucketStorageUnitCount(typeBits, bucketIndexingBits)
+
+	size := uint64(storageUnitsOffset)
+	for i := 0; i < bucketCount; i++ {
+
+		storageUnitCount := uint64(binary.LittleEndian.Uint32(data[binaryHeaderSize+i*4:]))
+		if storageUnitCount > maxBucketStorageUnitCount {
+			return 0, fmt.Errorf("%w: bucket %d has too many storage units", ErrInvalidBinaryData, i)
+		}
+
+		size += storageUnitCount * 8
+	}
+
+	if uint64(len(data)) != size {
+		return 0, fmt.Errorf("%w: expected %d bytes but got %d", ErrInvalidBinaryData, size, len(data))
+	}
+
+	return bucketIndexingBits, nil
+}
+
+//GobEncode encodes the set with MarshalBinary, which unlike the default gob encoding keeps the bucket layout of the set
+func (n *NSet[T]) GobEncode() ([]byte, error) {
+	return n.MarshalBinary()
+}
+
+func (n *NSet[T]) GobDecode(data []byte) error {
+	return n.UnmarshalBinary(data)
+}
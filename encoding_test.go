@@ -0,0 +1,73 @@
+package nset_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestNSetBinaryEncoding(t *testing.T) {
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 1, 63, 64, 1000, 10_000_000, math.MaxUint32)
+	n1.Remove(10_000_000)
+
+	data, err := n1.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	n2 := &nset.NSet[uint32]{}
+	AllTrue(t, n2.UnmarshalBinary(data) == nil)
+	AllTrue(t, n2.IsEq(n1), n2.ContainsAll(0, 1, 63, 64, 1000, math.MaxUint32), !n2.Contains(10_000_000))
+
+	//Empty storage units at the end of buckets aren't encoded
+	AllTrue(t, n2.StorageUnitCount < n1.StorageUnitCount)
+
+	//Layout is kept
+	n3 := nset.NewNSet[uint16](nset.WithBucketIndexingBits(2))
+	n3.AddMany(5, 500, math.MaxUint16)
+	data, err = n3.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	n4 := nset.NewNSet[uint16]()
+	AllTrue(t, n4.UnmarshalBinary(data) == nil)
+	IsEq(t, 4, len(n4.Buckets))
+	AllTrue(t, n4.IsEq(n3), n4.ContainsAll(5, 500, math.MaxUint16))
+
+	n4.Add(6)
+	AllTrue(t, n4.ContainsAll(5, 6, 500, math.MaxUint16))
+
+	//Invalid data
+	AllTrue(t, errors.Is(nset.NewNSet[uint32]().UnmarshalBinary(data), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(n4.UnmarshalBinary(data[:len(data)-1]), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(n4.UnmarshalBinary(nil), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(n4.UnmarshalBinary([]byte("NSET")), nset.ErrInvalidBinaryData))
+}
+
+func TestNSetGob(t *testing.T) {
+
+	type job struct {
+		Name string
+		IDs  *nset.NSet[uint32]
+	}
+
+	ids := nset.NewNSet[uint32](nset.WithBucketIndexingBits(3))
+	ids.AddMany(1, 2, 3, 100_000, math.MaxUint32)
+
+	buf := &bytes.Buffer{}
+	AllTrue(t, gob.NewEncoder(buf).Encode(job{Name: "a", IDs: ids}) == nil)
+
+	decoded := job{}
+	AllTrue(t, gob.NewDecoder(buf).Decode(&decoded) == nil)
+	IsEq(t, "a", decoded.Name)
+	IsEq(t, 8, len(decoded.IDs.Buckets))
+	AllTrue(t, decoded.IDs.IsEq(ids))
+
+	//The decoded set must index values the same way as the original
+	decoded.IDs.Add(math.MaxUint32 - 1)
+	AllTrue(t, decoded.IDs.ContainsAll(1, 2, 3, 100_000, math.MaxUint32-1, math.MaxUint32), !decoded.IDs.Contains(4))
+	IsEq(t, 6, decoded.IDs.Len())
+}
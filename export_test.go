@@ -0,0 +1,13 @@
+package nset
+
+//StoreWAL and WrapStoreWAL let tests put a WAL that fails in chosen ways under a Store
+type StoreWAL = storeWAL
+
+func WrapStoreWAL[T IntsIf](s *Store[T], wrap func(wal StoreWAL) StoreWAL) {
+	s.wal = wrap(s.wal)
+}
+
+//IndexPostingCount returns the number of posting lists held by the index, including empty ones
+func IndexPostingCount[K comparable](idx *Index[K]) int {
+	return len(idx.postings)
+}
@@ -0,0 +1,379 @@
+package nset
+
+import (
+	"math/bits"
+	"strings"
+	"unsafe"
+)
+
+//This file has the logic of Set8 and Set16, which both store a set as a flat list of storage units
+//where bit 'k' of data[j] is the value j*64+k. The data of both sets is always long enough for every value of T.
+
+func storageUnitsContains[T IntsIf](data []StorageType, x T) bool {
+	return data[uint64(x)/StorageTypeBits]&(1<<(uint64(x)%StorageTypeBits)) != 0
+}
+
+func storageUnitsAdd[T IntsIf](data []StorageType, x T) {
+	data[uint64(x)/StorageTypeBits] |= 1 << (uint64(x) % StorageTypeBits)
+}
+
+func storageUnitsContainsAny[T IntsIf](data []StorageType, values []T) bool {
+
+	for _, x := range values {
+		if storageUnitsContains(data, x) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func storageUnitsContainsAll[T IntsIf](data []StorageType, values []T) bool {
+
+	for _, x := range values {
+		if !storageUnitsContains(data, x) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func storageUnitsContainsMany[T IntsIf](data []StorageType, values []T, out []bool) {
+
+	out = out[:len(values)]
+	for i := 0; i < len(values); i++ {
+		out[i] = storageUnitsContains(data, values[i])
+	}
+}
+
+func storageUnitsContainsManyBitset[T IntsIf](data []StorageType, values []T, out []StorageType) {
+
+	out = out[:(len(values)+StorageTypeBits-1)/StorageTypeBits]
+	for i := 0; i < len(out); i++ {
+		out[i] = 0
+	}
+
+	for i := 0; i < len(values); i++ {
+		if storageUnitsContains(data, values[i]) {
+			out[i/StorageTypeBits] |= 1 << (i % StorageTypeBits)
+		}
+	}
+}
+
+//storageUnitsContainsManyInto adds to outData every value in values that is in data
+func storageUnitsContainsManyInto[T IntsIf](data []StorageType, values []T, outData []StorageType) {
+
+	for i := 0; i < len(values); i++ {
+		if storageUnitsContains(data, values[i]) {
+			storageUnitsAdd(outData, values[i])
+		}
+	}
+}
+
+func storageUnitsOnesCount(data []StorageType) int {
+
+	count := 0
+	for i := 0; i < len(data); i++ {
+		count += bits.OnesCount64(uint64(data[i]))
+	}
+
+	return count
+}
+
+//storageUnitsRank returns the number of set bits up to and including x's bit
+func storageUnitsRank[T IntsIf](data []StorageType, x T) int {
+
+	unitIndex := uint64(x) / StorageTypeBits
+	mask := StorageType(1) << (uint64(x) % StorageTypeBits)
+	return storageUnitsOnesCount(data[:unitIndex]) + bits.OnesCount64(uint64(data[unitIndex]&(mask|(mask-1))))
+}
+
+//storageUnitsElements returns the values of all set bits, where bit 'k' of data[j] is the value j*64+k
+func storageUnitsElements[T IntsIf](data []StorageType) []T {
+
+	elements := make([]T, 0, storageUnitsOnesCount(data))
+	storageUnitsForEach(data, func(x T) bool {
+		elements = append(elements, x)
+		return true
+	})
+
+	return elements
+}
+
+//storageUnitsForEach calls f on all set bits in ascending order, and stops early if f returns false
+func storageUnitsForEach[T IntsIf](data []StorageType, f func(x T) bool) {
+
+	for j := 0; j < len(data); j++ {
+
+		storageUnit := data[j]
+		firstStorageUnitValue := T(j * StorageTypeBits)
+		for storageUnit != 0 {
+
+			if !f(firstStorageUnitValue + T(bits.TrailingZeros64(uint64(storageUnit)))) {
+				return
+			}
+
+			//Clear the lowest set bit
+			storageUnit &= storageUnit - 1
+		}
+	}
+}
+
+func storageUnitsToMap[T IntsIf](data []StorageType) map[T]struct{} {
+
+	m := make(map[T]struct{}, storageUnitsOnesCount(data))
+	storageUnitsForEach(data, func(x T) bool {
+		m[x] = struct{}{}
+		return true
+	})
+
+	return m
+}
+
+//storageUnitsUnion adds the bits of otherData to data
+func storageUnitsUnion(data, otherData []StorageType) {
+
+	for i := 0; i < len(data); i++ {
+		data[i] |= otherData[i]
+	}
+}
+
+//storageUnitsIntersect clears the bits of data that are not in otherData
+func storageUnitsIntersect(data, otherData []StorageType) {
+
+	for i := 0; i < len(data); i++ {
+		data[i] &= otherData[i]
+	}
+}
+
+func storageUnitsHasIntersection(data, otherData []StorageType) bool {
+
+	for i := 0; i < len(data); i++ {
+		if data[i]&otherData[i] != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func storageUnitsIntersectionLen(data, otherData []StorageType) int {
+
+	count := 0
+	for i := 0; i < len(data); i++ {
+		count += bits.OnesCount64(uint64(data[i] & otherData[i]))
+	}
+
+	return count
+}
+
+func storageUnitsUnionLen(data, otherData []StorageType) int {
+
+	count := 0
+	for i := 0; i < len(data); i++ {
+		count += bits.OnesCount64(uint64(data[i] | otherData[i]))
+	}
+
+	return count
+}
+
+func storageUnitsDifferenceLen(data, otherData []StorageType) int {
+
+	count := 0
+	for i := 0; i < len(data); i++ {
+		count += bits.OnesCount64(uint64(data[i] &^ otherData[i]))
+	}
+
+	return count
+}
+
+//storageUnitsJaccardSimilarity is like NSet.JaccardSimilarity, so two empty sets return 1
+func storageUnitsJaccardSimilarity(data, otherData []StorageType) float64 {
+
+	unionCount := storageUnitsUnionLen(data, otherData)
+	if unionCount == 0 {
+		return 1
+	}
+
+	return float64(storageUnitsIntersectionLen(data, otherData)) / float64(unionCount)
+}
+
+//storageUnitsUnionWith adds all the elements of otherSet to data, for sets that are not the same type as data
+func storageUnitsUnionWith[T IntsIf](data []StorageType, otherSet Set[T]) {
+
+	otherSet.ForEach(func(x T) bool {
+		storageUnitsAdd(data, x)
+		return true
+	})
+}
+
+//storageUnitsIntersectWith removes all the elements that are not in otherSet from data, for sets that are not the same type as data
+func storageUnitsIntersectWith[T IntsIf](data []StorageType, otherSet Set[T]) {
+
+	storageUnitsForEach(data, func(x T) bool {
+		if !otherSet.Contains(x) {
+			data[uint64(x)/StorageTypeBits] &^= 1 << (uint64(x) % StorageTypeBits)
+		}
+		return true
+	})
+}
+
+//storageUnitsComplement flips all bits of values in [0, max] and clears the bits of values bigger than max
+func storageUnitsComplement[T IntsIf](data []StorageType, max T) {
+
+	lastUnitIndex := int(uint64(max) / StorageTypeBits)
+	for j := 0; j < lastUnitIndex; j++ {
+		data[j] = ^data[j]
+	}
+
+	//Only flip the bits of the last unit up to and including max
+	maxBitMask := StorageType(1) << (uint64(max) % StorageTypeBits)
+	data[lastUnitIndex] = (data[lastUnitIndex] ^ (maxBitMask | (maxBitMask - 1))) & (maxBitMask | (maxBitMask - 1))
+
+	for j := lastUnitIndex + 1; j < len(data); j++ {
+		data[j] = 0
+	}
+}
+
+//storageUnitsGetComplement sets outData to the values in [lo, hi] that are not in data. outData must be empty
+func storageUnitsGetComplement[T IntsIf](data []StorageType, lo, hi T, outData []StorageType) {
+
+	if lo > hi {
+		return
+	}
+
+	loUnitIndex := int(uint64(lo) / StorageTypeBits)
+	hiUnitIndex := int(uint64(hi) / StorageTypeBits)
+	for j := loUnitIndex; j <= hiUnitIndex; j++ {
+		outData[j] = ^data[j]
+	}
+
+	//Clear the bits before lo and after hi
+	hiBitMask := StorageType(1) << (uint64(hi) % StorageTypeBits)
+	outData[loUnitIndex] &^= 1<<(uint64(lo)%StorageTypeBits) - 1
+	outData[hiUnitIndex] &= hiBitMask | (hiBitMask - 1)
+}
+
+//storageUnitsIntervalsIter calls f on each run of consecutive set bits in ascending order, and stops early if f returns false
+func storageUnitsIntervalsIter[T IntsIf](data []StorageType, f func(start, end T) bool) {
+
+	hasRun := false
+	var runStart, runEnd T
+	for j := 0; j < len(data); j++ {
+
+		storageUnit := uint64(data[j])
+		firstStorageUnitValue := T(j * StorageTypeBits)
+		for storageUnit != 0 {
+
+			//Same as NSet.IntervalsIter
+			start := bits.TrailingZeros64(storageUnit)
+			onesCount := bits.TrailingZeros64(^(storageUnit >> start))
+
+			startValue := firstStorageUnitValue + T(start)
+			endValue := startValue + T(onesCount-1)
+			if hasRun && runEnd+1 == startValue {
+				runEnd = endValue
+			} else {
+
+				if hasRun && !f(runStart, runEnd) {
+					return
+				}
+
+				hasRun = true
+				runStart, runEnd = startValue, endValue
+			}
+
+			if start+onesCount >= StorageTypeBits {
+				break
+			}
+
+			storageUnit &^= 1<<(start+onesCount) - 1
+		}
+	}
+
+	if hasRun {
+		f(runStart, runEnd)
+	}
+}
+
+func storageUnitsIntervals[T IntsIf](data []StorageType) [][2]T {
+
+	intervals := make([][2]T, 0)
+	storageUnitsIntervalsIter(data, func(start, end T) bool {
+		intervals = append(intervals, [2]T{start, end})
+		return true
+	})
+
+	return intervals
+}
+
+//storageUnitsForEachChunk is NSet.forEachChunk for flat storage units, where chunk j is simply data[j]
+func storageUnitsForEachChunk(data []StorageType, f func(chunk uint64, storageUnit StorageType)) {
+
+	for j := 0; j < len(data); j++ {
+		if data[j] != 0 {
+			f(uint64(j), data[j])
+		}
+	}
+}
+
+//storageUnitsHash64 returns the same hash as NSet.Hash64 for a set with the same elements
+func storageUnitsHash64(data []StorageType) uint64 {
+
+	h := uint64(0)
+	for j := 0; j < len(data); j++ {
+		h ^= chunkHash(uint64(j), data[j])
+	}
+
+	return h
+}
+
+//storageUnitsFingerprint returns the same fingerprint as NSet.Fingerprint for a set with the same elements
+func storageUnitsFingerprint(data []StorageType) [32]byte {
+	return chunksFingerprint(func(f func(chunk uint64, storageUnit StorageType)) {
+		storageUnitsForEachChunk(data, f)
+	})
+}
+
+//storageUnitsStats returns the stats of data as a set with a single bucket
+func storageUnitsStats(data []StorageType) Stats {
+
+	s := Stats{
+		AllocatedBytes: uint64(len(data)) * uint64(unsafe.Sizeof(StorageType(0))),
+		Buckets:        make([]BucketStats, 1),
+	}
+
+	s.addBucket(&s.Buckets[0], data, StorageTypeBits)
+	return s
+}
+
+func storageUnitsString(data []StorageType) string {
+
+	b := strings.Builder{}
+	b.Grow(len(data)*StorageTypeBits + len(data)*2)
+
+	writeStorageUnitsString(&b, data)
+	return b.String()
+}
+
+//storageUnitsToNSet returns an NSet with the elements of data, which lets Set8 and Set16 use the encodings of NSet
+func storageUnitsToNSet[T IntsIf](data []StorageType) *NSet[T] {
+
+	n := NewNSet[T]()
+	n.AddSorted(storageUnitsElements[T](data))
+	return n
+}
+
+//storageUnitsFromNSet replaces the elements of data with the ones of n
+func storageUnitsFromNSet[T IntsIf](data []StorageType, n *NSet[T]) {
+
+	for j := 0; j < len(data); j++ {
+		data[j] = 0
+	}
+
+	n.lazyInit()
+	n.forEachChunk(func(chunk uint64, storageUnit StorageType) {
+		data[chunk] = storageUnit
+	})
+}
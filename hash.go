@@ -0,0 +1,194 @@
+package nset
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+//Hash64 returns a hash of the elements of the set. Sets with the same elements have the same hash, no matter their
+//bucket layout or how many storage units they have allocated, and the hash is the same across runs and machines.
+//
+//The first call hashes the whole set and keeps the hash of each bucket. Later changes update those hashes as they write
+//each storage unit, so calling Hash64 after a few changes is cheap. Changes that replace whole buckets (e.g. Union)
+//drop the cached hashes, and changes made directly to Buckets are not seen by them.
+//
+//Unlike other queries, Hash64 writes to the set when it fills the cache, so it must not be called concurrently with
+//other uses of the set, even if the set is otherwise only read.
+func (n *NSet[T]) Hash64() uint64 {
+
+	n.lazyInit()
+
+	//Buckets smaller than a storage unit share storage units with other buckets, so they can't be hashed on their own
+	if n.shiftAmount() < 6 {
+
+		h := uint64(0)
+		n.forEachChunk(func(chunk uint64, storageUnit StorageType) {
+			h ^= chunkHash(chunk, storageUnit)
+		})
+
+		return h
+	}
+
+	if len(n.bucketHashes) == 0 {
+
+		if cap(n.bucketHashes) < len(n.Buckets) {
+			n.bucketHashes = make([]uint64, 0, len(n.Buckets))
+		}
+
+		for i := 0; i < len(n.Buckets); i++ {
+			n.bucketHashes = append(n.bucketHashes, n.bucketHash(i))
+		}
+	}
+
+	h := uint64(0)
+	for i := 0; i < len(n.bucketHashes); i++ {
+		h ^= n.bucketHashes[i]
+	}
+
+	return h
+}
+
+//Fingerprint returns a SHA-256 hash of the elements of the set. Like Hash64, it doesn't depend on the bucket layout
+//or allocated storage units of the set, but is long enough that different sets can be assumed to have different fingerprints.
+//
+//The fingerprint is the SHA-256 of each non-empty 64 value chunk of the set in ascending order, written as
+//the chunk index (values [index*64, index*64+63]) followed by its storage unit, both as little endian uint64.
+func (n *NSet[T]) Fingerprint() [32]byte {
+
+	n.lazyInit()
+	return chunksFingerprint(n.forEachChunk)
+}
+
+//chunksFingerprint returns the SHA-256 of the chunks passed to f by forEachChunk, as described in NSet.Fingerprint
+func chunksFingerprint(forEachChunk func(f func(chunk uint64, storageUnit StorageType))) [32]byte {
+
+	h := sha256.New()
+	buf := make([]byte, 16)
+	forEachChunk(func(chunk uint64, storageUnit StorageType) {
+		binary.LittleEndian.PutUint64(buf, chunk)
+		binary.LittleEndian.PutUint64(buf[8:], uint64(storageUnit))
+		h.Write(buf)
+	})
+
+	var fingerprint [32]byte
+	h.Sum(fingerprint[:0])
+	return fingerprint
+}
+
+//bucketHash returns the XOR of the hashes of the non-empty storage units of bucket i. Only valid when buckets
+//are at least a storage unit big
+func (n *NSet[T]) bucketHash(i int) uint64 {
+
+	b := &n.Buckets[i]
+	firstChunk := uint64(i) << (n.shiftAmount() - 6)
+
+	h := uint64(0)
+	for j := 0; j < len(b.Data); j++ {
+		h ^= chunkHash(firstChunk+uint64(j), b.Data[j])
+	}
+
+	return h
+}
+
+//forEachChunk calls f in ascending order for every non-empty chunk of 64 values, where chunk i has values [i*64, i*64+63]
+//and bit k of its storage unit is the value i*64+k. Unlike storage units, chunks don't depend on the bucket layout
+func (n *NSet[T]) forEachChunk(f func(chunk uint64, storageUnit StorageType)) {
+
+	if n.shiftAmount() >= 6 {
+
+		for i := 0; i < len(n.Buckets); i++ {
+
+			b := &n.Buckets[i]
+			firstChunk := uint64(i) << (n.shiftAmount() - 6)
+			for j := 0; j < len(b.Data); j++ {
+				if b.Data[j] != 0 {
+					f(firstChunk+uint64(j), b.Data[j])
+				}
+			}
+		}
+
+		return
+	}
+
+	//Each bucket has at most one storage unit, and a few buckets make up one chunk
+	chunk := uint64(0)
+	storageUnit := StorageType(0)
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		if len(b.Data) == 0 || b.Data[0] == 0 {
+			continue
+		}
+
+		firstValue := uint64(i) << n.shiftAmount()
+		if firstValue/64 != chunk {
+
+			if storageUnit != 0 {
+				f(chunk, storageUnit)
+			}
+
+			chunk = firstValue / 64
+			storageUnit = 0
+		}
+
+		storageUnit |= b.Data[0] << (firstValue % 64)
+	}
+
+	if storageUnit != 0 {
+		f(chunk, storageUnit)
+	}
+}
+
+//storageUnitChanged updates the cached hash of bucket i after storage unit j changed from oldStorageUnit to newStorageUnit.
+//It must only be called when the hashes are cached (len(n.bucketHashes) != 0).
+func (n *NSet[T]) storageUnitChanged(i BucketType, j uint32, oldStorageUnit, newStorageUnit StorageType) {
+
+	if oldStorageUnit == newStorageUnit {
+		return
+	}
+
+	//XOR removes the hash of the old storage unit and adds the hash of the new one
+	chunk := uint64(i)<<(n.shiftAmount()-6) + uint64(j)
+	n.bucketHashes[i] ^= chunkHash(chunk, oldStorageUnit) ^ chunkHash(chunk, newStorageUnit)
+}
+
+//setStorageUnit writes storage unit j of bucket i and keeps the cached hashes up to date. The storage unit must be allocated
+func (n *NSet[T]) setStorageUnit(i BucketType, j uint32, storageUnit StorageType) {
+
+	b := &n.Buckets[i]
+	if len(n.bucketHashes) != 0 {
+		n.storageUnitChanged(i, j, b.Data[j], storageUnit)
+	}
+
+	b.Data[j] = storageUnit
+}
+
+//allBucketsChanged drops the cached hashes of all buckets, keeping their memory for the next Hash64
+func (n *NSet[T]) allBucketsChanged() {
+	n.bucketHashes = n.bucketHashes[:0]
+}
+
+//chunkHash is the hash of a 64 value chunk, where empty chunks have a hash of zero so allocated but empty storage units
+//don't change the hash
+func chunkHash(chunk uint64, storageUnit StorageType) uint64 {
+
+	if storageUnit == 0 {
+		return 0
+	}
+
+	return storageUnitHash(chunk, storageUnit)
+}
+
+//storageUnitHash mixes the position and value of a storage unit into a hash. Hashes of many storage units
+//are combined with XOR, which doesn't depend on their order
+func storageUnitHash(position uint64, storageUnit StorageType) uint64 {
+	return mix64(uint64(storageUnit) ^ mix64(position))
+}
+
+//mix64 is the finalizer of SplitMix64
+func mix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,92 @@
+package nset_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestNSetHash(t *testing.T) {
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 1, 63, 64, 1000, 10_000_000, math.MaxUint32)
+
+	//Different layout and extra allocated storage units
+	n2 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(2))
+	n2.Reserve(20_000_000)
+	n2.AddMany(math.MaxUint32, 10_000_000, 1000, 64, 63, 1, 0, 15_000_000)
+	n2.Remove(15_000_000)
+
+	AllTrue(t, n1.Hash64() == n2.Hash64(), n1.Fingerprint() == n2.Fingerprint())
+
+	//Small changes are seen, including after the hashes are cached
+	h := n1.Hash64()
+	f := n1.Fingerprint()
+	n1.Add(5)
+	AllTrue(t, n1.Hash64() != h, n1.Fingerprint() != f)
+
+	n1.Remove(5)
+	AllTrue(t, n1.Hash64() == h, n1.Fingerprint() == f)
+
+	for _, change := range []func(n *nset.NSet[uint32]){
+		func(n *nset.NSet[uint32]) { n.AddMany(7, 3) },
+		func(n *nset.NSet[uint32]) { n.AddSorted([]uint32{3, 7}) },
+		func(n *nset.NSet[uint32]) { n.Union(nset.FromSlice([]uint32{3})) },
+		func(n *nset.NSet[uint32]) { n.IntersectWith(nset.FromSlice([]uint32{0, 1})) },
+		func(n *nset.NSet[uint32]) { n.Complement(100) },
+		func(n *nset.NSet[uint32]) { AllTrue(t, n.Apply(nset.Diff(n, nset.FromSlice([]uint32{9}))) == nil) },
+	} {
+
+		n := n1.Copy()
+		before := n.Hash64()
+		change(n)
+
+		AllTrue(t, before == h, n.Hash64() != h, n.Hash64() == n.Copy().Hash64())
+		AllTrue(t, n.Fingerprint() == n.Copy().Fingerprint())
+	}
+
+	//The cached hashes are updated on each change, and match hashing the set from scratch
+	rand.Seed(RandSeed)
+	n3 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(3))
+	n3.Hash64()
+	for i := 0; i < 10_000; i++ {
+
+		x := rand.Uint32() % 100_000
+		if i%100 == 99 {
+			x = rand.Uint32()
+		}
+
+		if rand.Intn(3) == 0 {
+			n3.Remove(x)
+		} else {
+			n3.Add(x)
+		}
+
+		if i%1000 == 999 && n3.Hash64() != n3.Copy().Hash64() {
+			t.Fatalf("cached hash differs from a new hash after %d changes", i+1)
+		}
+	}
+
+	//Empty sets
+	AllTrue(t, nset.NewNSet[uint32]().Hash64() == (&nset.NSet[uint16]{}).Hash64())
+	AllTrue(t, nset.NewNSet[uint32]().Fingerprint() == nset.NewNSet[uint8]().Fingerprint())
+	AllTrue(t, nset.NewNSet[uint32]().Fingerprint() != n1.Fingerprint())
+
+	//Buckets smaller than a storage unit
+	for bits := uint8(0); bits <= 8; bits++ {
+
+		s1 := nset.NewNSet[uint8](nset.WithBucketIndexingBits(bits))
+		s1.AddMany(0, 5, 63, 64, 200, 255)
+		s2 := nset.FromSlice([]uint8{0, 5, 63, 64, 200, 255})
+
+		AllTrue(t, s1.Hash64() == s2.Hash64(), s1.Fingerprint() == s2.Fingerprint())
+
+		s1.Remove(200)
+		AllTrue(t, s1.Hash64() != s2.Hash64(), s1.Fingerprint() != s2.Fingerprint())
+	}
+
+	//Hashes only depend on elements, not the element type
+	AllTrue(t, nset.FromSlice([]uint8{1, 200}).Hash64() == nset.FromSlice([]uint32{1, 200}).Hash64())
+}
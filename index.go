@@ -0,0 +1,126 @@
+package nset
+
+//Index is a bitmap index, which maps each key (e.g. a value of a column) to the set of row IDs that have that key,
+//called its posting list. Queries for rows matching any or all of a few keys are done with set unions and intersections.
+//
+//An Index is not safe for concurrent use.
+type Index[K comparable] struct {
+	postings map[K]*NSet[uint32]
+	options  []Option
+}
+
+//NewIndex returns an empty index. The options are used to create the posting lists
+func NewIndex[K comparable](options ...Option) *Index[K] {
+	return &Index[K]{
+		postings: make(map[K]*NSet[uint32]),
+		options:  options,
+	}
+}
+
+//Insert adds rowID to the posting list of key
+func (idx *Index[K]) Insert(rowID uint32, key K) {
+
+	posting, ok := idx.postings[key]
+	if !ok {
+		posting = NewNSet[uint32](idx.options...)
+		idx.postings[key] = posting
+	}
+
+	posting.Add(rowID)
+}
+
+//Delete removes rowID from the posting list of key. A posting list that becomes empty is removed from the index
+func (idx *Index[K]) Delete(rowID uint32, key K) {
+
+	if posting, ok := idx.postings[key]; ok {
+		idx.deleteFromPosting(rowID, key, posting)
+	}
+}
+
+//DeleteRow removes rowID from the posting lists of all keys. Posting lists that become empty are removed from the index
+func (idx *Index[K]) DeleteRow(rowID uint32) {
+
+	//Deleting map entries while ranging over the map is allowed
+	for key, posting := range idx.postings {
+		idx.deleteFromPosting(rowID, key, posting)
+	}
+}
+
+//deleteFromPosting removes rowID from the posting list of key, and removes the posting list if it becomes empty
+func (idx *Index[K]) deleteFromPosting(rowID uint32, key K, posting *NSet[uint32]) {
+
+	if !posting.Contains(rowID) {
+		return
+	}
+
+	posting.Remove(rowID)
+
+	//The posting list can only have become empty if the storage unit rowID was in did, which saves scanning it on most deletes.
+	//Emptiness is checked on the posting list itself instead of keeping counts, so counts can't go wrong if it was changed through Lookup
+	b := &posting.Buckets[posting.bucketIndex(rowID)]
+	if b.Data[posting.storageUnitIndex(rowID)] == 0 && posting.isEmpty() {
+		delete(idx.postings, key)
+	}
+}
+
+//Lookup returns the row IDs that have key. The returned set is the posting list used by the index, so it must not be changed
+//(use Copy for that). If no row has key an empty set is returned.
+func (idx *Index[K]) Lookup(key K) *NSet[uint32] {
+
+	if posting, ok := idx.postings[key]; ok {
+		return posting
+	}
+
+	return NewNSet[uint32](idx.options...)
+}
+
+//Or returns a new set with the row IDs that have any of the keys
+func (idx *Index[K]) Or(keys ...K) *NSet[uint32] {
+
+	result := NewNSet[uint32](idx.options...)
+	for _, key := range keys {
+		if posting, ok := idx.postings[key]; ok {
+			result.Union(posting)
+		}
+	}
+
+	return result
+}
+
+//And returns a new set with the row IDs that have all of the keys. If no keys are given the returned set is empty
+func (idx *Index[K]) And(keys ...K) *NSet[uint32] {
+
+	if len(keys) == 0 {
+		return NewNSet[uint32](idx.options...)
+	}
+
+	first, ok := idx.postings[keys[0]]
+	if !ok {
+		return NewNSet[uint32](idx.options...)
+	}
+
+	//A single key still returns a new set, so the result can be changed without changing the index
+	result := first.Copy()
+	for _, key := range keys[1:] {
+
+		posting, ok := idx.postings[key]
+		if !ok || !result.HasIntersection(posting) {
+			return NewNSet[uint32](idx.options...)
+		}
+
+		result = result.GetIntersection(posting)
+	}
+
+	return result
+}
+
+//Keys returns the keys that have at least one row, in no particular order
+func (idx *Index[K]) Keys() []K {
+
+	keys := make([]K, 0, len(idx.postings))
+	for key := range idx.postings {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
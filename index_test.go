@@ -0,0 +1,95 @@
+package nset_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestIndex(t *testing.T) {
+
+	type row struct {
+		color string
+		size  string
+	}
+
+	rows := []row{
+		{"red", "small"},
+		{"blue", "small"},
+		{"red", "large"},
+		{"green", "large"},
+		{"red", "small"},
+	}
+
+	colors := nset.NewIndex[string]()
+	sizes := nset.NewIndex[string](nset.WithBucketIndexingBits(2))
+	for i, r := range rows {
+		colors.Insert(uint32(i), r.color)
+		sizes.Insert(uint32(i), r.size)
+	}
+
+	AllTrue(t, colors.Lookup("red").IsEq(nset.FromSlice([]uint32{0, 2, 4})))
+	AllTrue(t, colors.Lookup("purple").Len() == 0)
+
+	AllTrue(t, colors.Or("red", "blue", "purple").IsEq(nset.FromSlice([]uint32{0, 1, 2, 4})))
+	AllTrue(t, colors.Or().Len() == 0)
+
+	AllTrue(t, colors.And("red").IsEq(colors.Lookup("red")))
+	AllTrue(t, colors.And("red", "blue").Len() == 0, colors.And("red", "purple").Len() == 0, colors.And().Len() == 0)
+
+	//Queries across indexes with different layouts
+	redAndSmall := colors.Lookup("red").GetIntersection(sizes.Lookup("small"))
+	AllTrue(t, redAndSmall.IsEq(nset.FromSlice([]uint32{0, 4})))
+
+	//Results don't share memory with the index
+	result := colors.And("red")
+	result.Add(100)
+	AllTrue(t, !colors.Lookup("red").Contains(100))
+
+	//Deleting
+	colors.Delete(0, "red")
+	colors.Delete(0, "purple")
+	AllTrue(t, colors.Lookup("red").IsEq(nset.FromSlice([]uint32{2, 4})))
+
+	colors.DeleteRow(3)
+	AllTrue(t, colors.Lookup("green").Len() == 0)
+
+	//Empty posting lists are removed, so deleted keys don't hold memory
+	IsEq(t, 2, nset.IndexPostingCount(colors))
+	colors.Insert(3, "green")
+	colors.Insert(3, "green")
+	colors.Delete(3, "green")
+	IsEq(t, 2, nset.IndexPostingCount(colors))
+
+	keys := colors.Keys()
+	sort.Strings(keys)
+	IsEq(t, 2, len(keys))
+	IsEq(t, "blue", keys[0])
+	IsEq(t, "red", keys[1])
+
+	sizes.DeleteRow(1)
+	sizes.DeleteRow(2)
+	sizes.DeleteRow(3)
+	IsEq(t, 1, nset.IndexPostingCount(sizes))
+	AllTrue(t, sizes.Lookup("large").Len() == 0, sizes.Lookup("small").IsEq(nset.FromSlice([]uint32{0, 4})))
+
+	//Rows added through Lookup are not lost when other rows of the key are deleted
+	blue := colors.Lookup("blue")
+	blueRows := blue.GetAllElements()
+	blue.Add(1_000_000)
+	for _, rowID := range blueRows {
+		colors.Delete(rowID, "blue")
+	}
+	AllTrue(t, colors.Lookup("blue").IsEq(nset.FromSlice([]uint32{1_000_000})))
+
+	colors.Delete(1_000_000, "blue")
+	IsEq(t, 1, nset.IndexPostingCount(colors))
+
+	//Keys of other types
+	ages := nset.NewIndex[int]()
+	ages.Insert(1, 30)
+	ages.Insert(2, 30)
+	ages.Insert(1_000_000, 40)
+	AllTrue(t, ages.Or(30, 40).IsEq(nset.FromSlice([]uint32{1, 2, 1_000_000})), ages.And(30, 40).Len() == 0)
+}
@@ -0,0 +1,13 @@
+//Package access lets package lowlevel read the storage of an nset.NSet without using its deprecated exported fields,
+//so those fields can later be unexported without changing lowlevel.
+//
+//The functions are set by package nset when it is initialized, which always happens before lowlevel uses them
+//as lowlevel imports nset. They take a *nset.NSet[T] as 'any', since package nset can't be imported here.
+package access
+
+var (
+	//StorageUnits returns the storage units of bucket bucketIndex of n as a []nset.StorageType, making sure n has its buckets first
+	StorageUnits func(n any, bucketIndex int) any
+	//StorageUnitCount returns the number of storage units used by all buckets of n
+	StorageUnitCount func(n any) uint32
+)
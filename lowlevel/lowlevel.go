@@ -0,0 +1,60 @@
+//Package lowlevel gives read access to how an nset.NSet stores its values, for advanced uses like custom
+//encodings or algorithms working on whole storage units.
+//
+//Values are split by their top bits into buckets, and each bucket stores its values as bits in a slice of
+//storage units, where bit 'k' of storage unit 'j' is the value j*64+k after removing the bucket bits.
+//
+//The functions here replace the deprecated NSet.GetBucketIndex, NSet.GetStorageUnitIndex, NSet.GetBitMask and
+//direct use of NSet.Buckets. Unlike those, nothing here gives a way to change the set.
+package lowlevel
+
+import (
+	"unsafe"
+
+	"github.com/bloeys/nset"
+	"github.com/bloeys/nset/internal/access"
+)
+
+//BucketCount returns the number of buckets in the set
+func BucketCount[T nset.IntsIf](n *nset.NSet[T]) int {
+	return 1 << n.BucketIndexingBits()
+}
+
+//BucketIndex returns the index of the bucket that x is stored in
+func BucketIndex[T nset.IntsIf](n *nset.NSet[T], x T) int {
+	return int(x >> shiftAmount(n))
+}
+
+//StorageUnitIndex returns the index of the storage unit that x is stored in, within the storage units of its bucket
+func StorageUnitIndex[T nset.IntsIf](n *nset.NSet[T], x T) uint32 {
+	return uint32(withoutBucketBits(n, x) / nset.StorageTypeBits)
+}
+
+//BitMask returns the storage unit bit that is set when x is in the set
+func BitMask[T nset.IntsIf](n *nset.NSet[T], x T) nset.StorageType {
+	return 1 << (withoutBucketBits(n, x) % nset.StorageTypeBits)
+}
+
+//StorageUnits returns the storage units of the bucket with the passed index.
+//
+//The returned slice shares memory with the set so it must not be changed, and it is only valid until the set is changed.
+//Its capacity is the same as its length, so appending to it allocates a new slice instead of writing into the set.
+func StorageUnits[T nset.IntsIf](n *nset.NSet[T], bucketIndex int) []nset.StorageType {
+
+	data := access.StorageUnits(n, bucketIndex).([]nset.StorageType)
+	return data[:len(data):len(data)]
+}
+
+//StorageUnitCount returns the number of storage units used by all buckets in the set
+func StorageUnitCount[T nset.IntsIf](n *nset.NSet[T]) uint32 {
+	return access.StorageUnitCount(n)
+}
+
+func shiftAmount[T nset.IntsIf](n *nset.NSet[T]) uint8 {
+	return uint8(unsafe.Sizeof(T(0))*8) - n.BucketIndexingBits()
+}
+
+func withoutBucketBits[T nset.IntsIf](n *nset.NSet[T], x T) T {
+	bucketIndexingBits := n.BucketIndexingBits()
+	return (x << bucketIndexingBits) >> bucketIndexingBits
+}
@@ -0,0 +1,70 @@
+package lowlevel_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bloeys/nset"
+	"github.com/bloeys/nset/lowlevel"
+)
+
+func TestLowLevel(t *testing.T) {
+
+	n := nset.NewNSet[uint32]()
+	n.AddMany(0, 1, 63, 64, 1000, math.MaxUint32)
+
+	IsEq(t, nset.BucketCount, lowlevel.BucketCount(n))
+	IsEq(t, nset.BucketCount-1, lowlevel.BucketIndex(n, math.MaxUint32))
+	IsEq(t, math.MaxUint32/64/nset.BucketCount, lowlevel.StorageUnitIndex(n, math.MaxUint32))
+	IsEq(t, 1<<63, lowlevel.BitMask(n, math.MaxUint32))
+	IsEq(t, uint32(n.Stats().UsedStorageUnits), lowlevel.StorageUnitCount(n))
+
+	//Must match the deprecated NSet methods
+	for _, x := range []uint32{0, 1, 63, 64, 1000, 1 << 25, 1<<25 - 1, math.MaxUint32} {
+		IsEq(t, int(n.GetBucketIndex(x)), lowlevel.BucketIndex(n, x))
+		IsEq(t, n.GetStorageUnitIndex(x), lowlevel.StorageUnitIndex(n, x))
+		IsEq(t, n.GetBitMask(x), lowlevel.BitMask(n, x))
+	}
+
+	units := lowlevel.StorageUnits(n, 0)
+	IsEq(t, 16, len(units))
+	IsEq(t, nset.StorageType(1<<0|1<<1|1<<63), units[0])
+	IsEq(t, nset.StorageType(1<<0), units[1])
+
+	//Appending must not change the set
+	appended := append(units, math.MaxUint64)
+	IsEq(t, 17, len(appended))
+	AllTrue(t, !n.Contains(16*64), n.Len() == 6, len(lowlevel.StorageUnits(n, 0)) == 16)
+
+	//Other layouts and zero values
+	small := nset.NewNSet[uint8](nset.WithBucketIndexingBits(2))
+	small.Add(200)
+	IsEq(t, 4, lowlevel.BucketCount(small))
+	IsEq(t, 3, lowlevel.BucketIndex(small, 200))
+	IsEq(t, nset.StorageType(1<<(200-192)), lowlevel.StorageUnits(small, 3)[0])
+
+	var zero nset.NSet[uint16]
+	IsEq(t, 0, len(lowlevel.StorageUnits(&zero, 5)))
+	IsEq(t, nset.BucketCount, lowlevel.BucketCount(&zero))
+}
+
+func AllTrue(t *testing.T, values ...bool) bool {
+
+	for i := 0; i < len(values); i++ {
+		if !values[i] {
+			t.Errorf("Expected 'true' but got 'false'\n")
+		}
+	}
+
+	return true
+}
+
+func IsEq[T comparable](t *testing.T, expected, val T) bool {
+
+	if val == expected {
+		return true
+	}
+
+	t.Errorf("Expected '%v' but got '%v'\n", expected, val)
+	return false
+}
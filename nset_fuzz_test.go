@@ -0,0 +1,179 @@
+package nset_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/bloeys/nset"
+)
+
+const (
+	fuzzOpAddA = iota
+	fuzzOpAddB
+	fuzzOpRemoveA
+	fuzzOpRemoveB
+	fuzzOpUnion
+	fuzzOpGetIntersection
+	fuzzOpCopy
+	fuzzOpIsEq
+	fuzzOpGetAllElements
+	fuzzOpCount
+
+	//fuzzMaxOps limits the work done per input, as every op on a big value might allocate a few MBs
+	fuzzMaxOps = 256
+)
+
+func FuzzNSetUint8(f *testing.F) {
+	fuzzNSet[uint8](f)
+}
+
+func FuzzNSetUint16(f *testing.F) {
+	fuzzNSet[uint16](f)
+}
+
+func FuzzNSetUint32(f *testing.F) {
+	fuzzNSet[uint32](f)
+}
+
+func fuzzNSet[T nset.IntsIf](f *testing.F) {
+
+	f.Add([]byte{})
+	f.Add([]byte{0x77, fuzzOpAddA, 5, 0, 0, 0, fuzzOpRemoveA, 5, 0, 0, 0, fuzzOpIsEq})
+	f.Add([]byte{0x77, fuzzOpRemoveA, 5, 0, 0, 0, fuzzOpAddB, 200, 1, 0, 0, fuzzOpIsEq, fuzzOpGetAllElements})
+	f.Add([]byte{0x27, fuzzOpAddA, 255, 255, 255, 255, fuzzOpAddB, 1, 0, 0, 0, fuzzOpUnion, fuzzOpCopy, fuzzOpIsEq, fuzzOpGetIntersection, fuzzOpGetAllElements})
+	f.Add([]byte{0x80, fuzzOpAddA, 64, 0, 0, 128, fuzzOpAddA, 63, 0, 0, 128, fuzzOpAddB, 64, 0, 0, 128, fuzzOpGetIntersection, fuzzOpRemoveA, 64, 0, 0, 128, fuzzOpIsEq})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runFuzzOps[T](t, data)
+	})
+}
+
+//runFuzzOps runs the operations encoded in data on two NSets (A and B) and on two map models of them,
+//and checks that the NSets always have the same elements as their models.
+//
+//The first byte selects the bucket layouts of A and B, then each op is a byte followed by a little endian value for ops that take one.
+func runFuzzOps[T nset.IntsIf](t *testing.T, data []byte) {
+
+	if len(data) == 0 {
+		return
+	}
+
+	//Buckets are limited to 2^25 values (4 MB), like the default uint32 layout, to keep big values from using a lot of memory
+	valueSize := int(unsafe.Sizeof(T(0)))
+	minBucketBits := uint8(0)
+	if valueSize*8 > 25 {
+		minBucketBits = uint8(valueSize*8 - 25)
+	}
+
+	layoutA := nset.WithBucketIndexingBits(minBucketBits + data[0]&0x0F%(nset.MaxBucketIndexingBits+1-minBucketBits))
+	layoutB := nset.WithBucketIndexingBits(minBucketBits + data[0]>>4%(nset.MaxBucketIndexingBits+1-minBucketBits))
+	data = data[1:]
+
+	a, modelA := nset.NewNSet[T](layoutA), map[T]struct{}{}
+	b, modelB := nset.NewNSet[T](layoutB), map[T]struct{}{}
+
+	readValue := func() T {
+
+		var x T
+		for i := 0; i < valueSize && i < len(data); i++ {
+			x |= T(data[i]) << (i * 8)
+		}
+
+		if len(data) < valueSize {
+			data = data[len(data):]
+		} else {
+			data = data[valueSize:]
+		}
+
+		return x
+	}
+
+	for opCount := 0; len(data) > 0 && opCount < fuzzMaxOps; opCount++ {
+
+		op := data[0] % fuzzOpCount
+		data = data[1:]
+
+		switch op {
+		case fuzzOpAddA:
+			x := readValue()
+			a.Add(x)
+			modelA[x] = struct{}{}
+		case fuzzOpAddB:
+			x := readValue()
+			b.Add(x)
+			modelB[x] = struct{}{}
+		case fuzzOpRemoveA:
+			x := readValue()
+			a.Remove(x)
+			delete(modelA, x)
+		case fuzzOpRemoveB:
+			x := readValue()
+			b.Remove(x)
+			delete(modelB, x)
+		case fuzzOpUnion:
+			a.Union(b)
+			for x := range modelB {
+				modelA[x] = struct{}{}
+			}
+		case fuzzOpGetIntersection:
+			intersection := a.GetIntersection(b)
+			modelIntersection := map[T]struct{}{}
+			for x := range modelA {
+				if _, ok := modelB[x]; ok {
+					modelIntersection[x] = struct{}{}
+				}
+			}
+			assertSameAsModel(t, intersection, modelIntersection)
+			a, modelA = intersection, modelIntersection
+		case fuzzOpCopy:
+			b = a.Copy()
+			modelB = make(map[T]struct{}, len(modelA))
+			for x := range modelA {
+				modelB[x] = struct{}{}
+			}
+		case fuzzOpIsEq:
+			modelsEq := len(modelA) == len(modelB)
+			for x := range modelA {
+				if _, ok := modelB[x]; !ok {
+					modelsEq = false
+					break
+				}
+			}
+			if a.IsEq(b) != modelsEq || b.IsEq(a) != modelsEq {
+				t.Fatalf("IsEq returned %v but expected %v. A=%v; B=%v\n", a.IsEq(b), modelsEq, a.GetAllElements(), b.GetAllElements())
+			}
+			//The sets keep their cached hashes between ops, so this also checks the caches are cleared on changes
+			if modelsEq && (a.Hash64() != b.Hash64() || a.Fingerprint() != b.Fingerprint()) {
+				t.Fatalf("Equal sets have different hashes. A=%v; B=%v\n", a.GetAllElements(), b.GetAllElements())
+			}
+		case fuzzOpGetAllElements:
+			elements := a.GetAllElements()
+			if len(elements) != len(modelA) {
+				t.Fatalf("GetAllElements returned %d elements but expected %d\n", len(elements), len(modelA))
+			}
+			for i := 0; i < len(elements); i++ {
+				if _, ok := modelA[elements[i]]; !ok || (i > 0 && elements[i-1] >= elements[i]) {
+					t.Fatalf("GetAllElements returned unexpected or unsorted elements: %v\n", elements)
+				}
+			}
+		}
+	}
+
+	assertSameAsModel(t, a, modelA)
+	assertSameAsModel(t, b, modelB)
+}
+
+func assertSameAsModel[T nset.IntsIf](t *testing.T, n *nset.NSet[T], model map[T]struct{}) {
+
+	t.Helper()
+
+	if n.Len() != len(model) {
+		t.Fatalf("NSet has %d elements but expected %d\n", n.Len(), len(model))
+	}
+
+	for x := range model {
+		if !n.Contains(x) {
+			t.Fatalf("NSet is missing '%v'\n", x)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package nset_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"testing"
 
 	"github.com/bloeys/nset"
@@ -96,6 +98,463 @@ func TestNSet(t *testing.T) {
 	AllTrue(t, len(n8Elements) == 5, n8Elements[0] == 0, n8Elements[1] == 1, n8Elements[2] == 55, n8Elements[3] == 1000, n8Elements[4] == 10000)
 }
 
+func TestNSetSetAlgebraLen(t *testing.T) {
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 1, 63, 64, 1000, math.MaxUint32)
+
+	n2 := nset.NewNSet[uint32]()
+	n2.AddMany(1, 64, 999, 100_000)
+
+	IsEq(t, 2, n1.IntersectionLen(n2))
+	IsEq(t, 2, n2.IntersectionLen(n1))
+	IsEq(t, len(n1.GetIntersection(n2).GetAllElements()), n1.IntersectionLen(n2))
+
+	IsEq(t, 8, n1.UnionLen(n2))
+	IsEq(t, 8, n2.UnionLen(n1))
+	IsEq(t, len(nset.UnionSets(n1, n2).GetAllElements()), n1.UnionLen(n2))
+
+	IsEq(t, 4, n1.DifferenceLen(n2))
+	IsEq(t, 2, n2.DifferenceLen(n1))
+
+	IsEq(t, 0.25, n1.JaccardSimilarity(n2))
+	IsEq(t, 1.0, n1.JaccardSimilarity(n1))
+	IsEq(t, 1.0, nset.NewNSet[uint32]().JaccardSimilarity(nset.NewNSet[uint32]()))
+	IsEq(t, 0.0, n1.JaccardSimilarity(nset.NewNSet[uint32]()))
+}
+
+func TestNSetContainsMany(t *testing.T) {
+
+	n := nset.NewNSet[uint32]()
+	n.AddMany(0, 5, 64, 1000, math.MaxUint32)
+
+	probes := []uint32{0, 1, 5, 64, 65, math.MaxUint32, 1000, math.MaxUint32 - 1, 5}
+	expected := []bool{true, false, true, true, false, true, true, false, true}
+
+	results := make([]bool, len(probes))
+	n.ContainsMany(probes, results)
+	for i := 0; i < len(expected); i++ {
+		IsEq(t, expected[i], results[i])
+	}
+
+	bitset := []nset.StorageType{math.MaxUint64}
+	n.ContainsManyBitset(probes, bitset)
+	IsEq(t, nset.StorageType(0b1_0110_1101), bitset[0])
+
+	found := nset.NewNSet[uint32]()
+	n.ContainsManyInto(probes, found)
+	AllTrue(t, found.ContainsAll(0, 5, 64, 1000, math.MaxUint32), !found.ContainsAny(1, 65, math.MaxUint32-1))
+
+	//Big batches are grouped by bucket but must give the same results in the same order
+	rand.Seed(RandSeed)
+	added := make([]uint32, 10_000)
+	for i := 0; i < len(added); i++ {
+		added[i] = rand.Uint32()
+		n.Add(added[i])
+	}
+
+	//A third of the probes are in the set, and the rest are likely not
+	probes = make([]uint32, 50_000)
+	for i := 0; i < len(probes); i++ {
+		probes[i] = added[rand.Intn(len(added))] + uint32(i%3)
+	}
+
+	results = make([]bool, len(probes))
+	n.ContainsMany(probes, results)
+
+	bitset = make([]nset.StorageType, (len(probes)+63)/64)
+	n.ContainsManyBitset(probes, bitset)
+
+	found = nset.NewNSet[uint32]()
+	n.ContainsManyInto(probes, found)
+
+	foundCount := 0
+	for i := 0; i < len(probes); i++ {
+
+		expectedFound := n.Contains(probes[i])
+		if expectedFound {
+			foundCount++
+		}
+
+		if !IsEq(t, expectedFound, results[i]) || !IsEq(t, expectedFound, bitset[i/64]&(1<<(i%64)) != 0) || !IsEq(t, expectedFound, found.Contains(probes[i])) {
+			break
+		}
+	}
+	AllTrue(t, foundCount > 0, found.Len() <= foundCount)
+}
+
+func TestNSetAddSorted(t *testing.T) {
+
+	sorted := []uint32{0, 0, 1, 63, 64, 65, 1000, 1_000_000, 1_000_001, math.MaxUint32 - 1, math.MaxUint32}
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddSorted(sorted)
+
+	n2 := nset.NewNSet[uint32]()
+	for i := 0; i < len(sorted); i++ {
+		n2.Add(sorted[i])
+	}
+
+	n3 := nset.NewNSet[uint32]()
+	n3.AddMany(sorted...)
+
+	AllTrue(t, n1.IsEq(n2), n3.IsEq(n2), n1.ContainsAll(sorted...), !n1.ContainsAny(2, 66, 999_999))
+
+	//Unsorted values must still be added correctly
+	n4 := nset.NewNSet[uint32]()
+	n4.AddSorted([]uint32{math.MaxUint32, 1000, 0, 65, 64, 1_000_001, 1, 63, 1_000_000, math.MaxUint32 - 1})
+	AllTrue(t, n4.IsEq(n2))
+
+	n5 := nset.NewNSet[uint8]()
+	n5.AddSorted([]uint8{0, 1, 2, 3, 127, 128, 254, 255})
+	AllTrue(t, n5.ContainsAll(0, 1, 2, 3, 127, 128, 254, 255), !n5.ContainsAny(4, 126, 129, 253))
+}
+
+func TestNSetConversions(t *testing.T) {
+
+	values := []uint32{1000, 0, 63, 64, math.MaxUint32, 1000, 1_000_000}
+
+	n1 := nset.FromSlice(values)
+	n2 := nset.NewNSet[uint32]()
+	n2.AddMany(values...)
+	AllTrue(t, n1.IsEq(n2), n1.ContainsAll(values...), !n1.ContainsAny(1, 65, 999))
+
+	m := n1.ToMap()
+	IsEq(t, 6, len(m))
+	for i := 0; i < len(values); i++ {
+		_, ok := m[values[i]]
+		AllTrue(t, ok)
+	}
+
+	n3 := nset.FromMapKeys(map[uint32]string{1000: "a", 0: "b", 63: "c", 64: "d", math.MaxUint32: "e", 1_000_000: "f"})
+	AllTrue(t, n3.IsEq(n1))
+
+	//Each bucket is allocated once, on top of the allocations of an empty set
+	emptyAllocs := testing.AllocsPerRun(1, func() { nset.NewNSet[uint32]() })
+	ascending := make([]uint32, 100_000)
+	ascendingMap := make(map[uint32]struct{}, len(ascending))
+	for i := 0; i < len(ascending); i++ {
+		ascending[i] = uint32(i)
+		ascendingMap[uint32(i)] = struct{}{}
+	}
+
+	IsEq(t, emptyAllocs+1, testing.AllocsPerRun(1, func() { nset.FromSlice(ascending) }))
+	IsEq(t, emptyAllocs+1, testing.AllocsPerRun(1, func() { nset.FromMapKeys(ascendingMap) }))
+	IsEq(t, emptyAllocs+2, testing.AllocsPerRun(1, func() { nset.FromSlice(values) }))
+	AllTrue(t, nset.FromSlice(ascending).IsEq(nset.FromRange[uint32](0, 99_999)), nset.FromMapKeys(ascendingMap).IsEq(nset.FromSlice(ascending)))
+
+	//Ranges
+	n4 := nset.FromRange[uint32](60, 200)
+	AllTrue(t, n4.ContainsAll(60, 63, 64, 127, 128, 200), !n4.ContainsAny(0, 59, 201, 256))
+	IsEq(t, 141, len(n4.GetAllElements()))
+
+	n5 := nset.FromRange[uint32](math.MaxUint32-100, math.MaxUint32)
+	IsEq(t, 101, len(n5.GetAllElements()))
+	AllTrue(t, n5.ContainsAll(math.MaxUint32-100, math.MaxUint32), !n5.Contains(math.MaxUint32-101))
+
+	//Range crossing a bucket boundary
+	bucketSize := uint32(1 << (32 - nset.BucketIndexingBits))
+	n6 := nset.FromRange(bucketSize-70, bucketSize+70)
+	IsEq(t, 141, len(n6.GetAllElements()))
+	AllTrue(t, n6.ContainsAll(bucketSize-70, bucketSize-1, bucketSize, bucketSize+70), !n6.ContainsAny(bucketSize-71, bucketSize+71))
+
+	IsEq(t, 0, len(nset.FromRange[uint32](10, 5).GetAllElements()))
+
+	n7 := nset.FromRange[uint8](0, math.MaxUint8)
+	IsEq(t, 256, len(n7.GetAllElements()))
+	IsEq(t, 256, len(n7.ToMap()))
+}
+
+func TestNSetReserve(t *testing.T) {
+
+	const max = 100_000
+
+	n1 := nset.NewNSetWithMax[uint32](max)
+	IsEq(t, 0, n1.StorageUnitCount)
+	AllTrue(t, n1.IsEq(nset.NewNSet[uint32]()))
+
+	//AllocsPerRun warms up by calling f once, which would do the growth even if Reserve didn't,
+	//so count the allocations of a single call instead
+	allocs := mallocsDuring(func() {
+		for i := uint32(0); i <= max; i++ {
+			n1.Add(i)
+		}
+	})
+	IsEq(t, uint64(0), allocs)
+	AllTrue(t, n1.ContainsAll(0, 1, max/2, max), !n1.Contains(max+1))
+
+	//Without Reserve the same adds allocate
+	n3 := nset.NewNSet[uint32]()
+	AllTrue(t, mallocsDuring(func() {
+		for i := uint32(0); i <= max; i++ {
+			n3.Add(i)
+		}
+	}) > 0)
+
+	//Reserving on a set with data must keep the data
+	n2 := nset.NewNSet[uint32]()
+	n2.AddMany(0, 64, 1000)
+	n2.Reserve(math.MaxUint32 / 2)
+	AllTrue(t, n2.ContainsAll(0, 64, 1000), !n2.ContainsAny(1, 65, 999))
+
+	allocs = mallocsDuring(func() {
+		n2.AddMany(5, 100_000, math.MaxUint32/2)
+	})
+	IsEq(t, uint64(0), allocs)
+	AllTrue(t, n2.ContainsAll(0, 5, 64, 1000, 100_000, math.MaxUint32/2))
+}
+
+//mallocsDuring returns the number of heap allocations done while running f once
+func mallocsDuring(f func()) uint64 {
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+
+	return after.Mallocs - before.Mallocs
+}
+
+func TestNSetBucketConfigs(t *testing.T) {
+
+	for bits := uint8(0); bits <= nset.MaxBucketIndexingBits+1; bits++ {
+		testBucketConfig(t, bits, []uint8{0, 1, 2, 63, 64, 127, 128, 200, math.MaxUint8})
+		testBucketConfig(t, bits, []uint16{0, 1, 63, 64, 1000, 30_000, math.MaxUint16 - 1, math.MaxUint16})
+		testBucketConfig(t, bits, []uint32{0, 1, 63, 64, 1000, 10_000_000, 1<<28 - 1, 1 << 28})
+	}
+}
+
+func testBucketConfig[T nset.IntsIf](t *testing.T, bits uint8, values []T) {
+
+	n1 := nset.NewNSet[T](nset.WithBucketIndexingBits(bits))
+	n1.AddMany(values...)
+	n1.Remove(values[1])
+
+	expectedBuckets := 1 << bits
+	if bits > nset.MaxBucketIndexingBits {
+		expectedBuckets = 1 << nset.MaxBucketIndexingBits
+	}
+	IsEq(t, expectedBuckets, len(n1.Buckets))
+
+	AllTrue(t, n1.ContainsAll(values[0]), n1.ContainsAll(values[2:]...), !n1.Contains(values[1]), !n1.Contains(values[0]+3))
+	IsEq(t, len(values)-1, n1.Len())
+
+	elements := n1.GetAllElements()
+	IsEq(t, len(values)-1, len(elements))
+	for i := 1; i < len(elements); i++ {
+		AllTrue(t, elements[i-1] < elements[i])
+	}
+
+	//Operations with a set of the same layout
+	n2 := nset.NewNSet[T](nset.WithBucketIndexingBits(bits))
+	n2.AddMany(values[1], values[2], values[len(values)-1])
+	IsEq(t, 2, n1.IntersectionLen(n2))
+	IsEq(t, len(values), n1.UnionLen(n2))
+	AllTrue(t, n1.HasIntersection(n2), n1.GetIntersection(n2).ContainsAll(values[2], values[len(values)-1]))
+	AllTrue(t, nset.UnionSets(n1, n2).ContainsAll(values...), n1.Copy().IsEq(n1))
+
+	n3 := nset.FromRange(values[0], values[0]+T(100))
+	n3Same := nset.NewNSet[T](nset.WithBucketIndexingBits(bits))
+	for x := values[0]; x <= values[0]+T(100); x++ {
+		n3Same.Add(x)
+	}
+	AllTrue(t, n3.IsEq(n3Same), n3Same.IsEq(n3))
+
+	//Operations with a set of the default layout
+	n4 := nset.NewNSet[T]()
+	n4.AddMany(values[1], values[2], values[len(values)-1])
+	IsEq(t, 2, n1.IntersectionLen(n4))
+	IsEq(t, len(values), n1.UnionLen(n4))
+	IsEq(t, len(values)-3, n1.DifferenceLen(n4))
+	AllTrue(t, n1.HasIntersection(n4), n1.GetIntersection(n4).IsEq(n2.GetIntersection(n1)), nset.UnionSets(n1, n4).ContainsAll(values...))
+
+	n1.Union(n4)
+	AllTrue(t, n1.ContainsAll(values...), nset.FromSlice(values).IsEq(n1), n1.IsEq(nset.FromSlice(values)))
+}
+
+func TestNSetZeroValue(t *testing.T) {
+
+	var n1 nset.NSet[uint32]
+	AllTrue(t, !n1.Contains(5), n1.Len() == 0)
+
+	n1.AddMany(0, 5, 1000, math.MaxUint32)
+	n1.Remove(5)
+	AllTrue(t, n1.ContainsAll(0, 1000, math.MaxUint32), !n1.Contains(5))
+	IsEq(t, nset.BucketCount, len(n1.Buckets))
+	IsEq(t, nset.BucketCount-1, n1.GetBucketIndex(math.MaxUint32))
+
+	n2 := nset.NewNSet[uint32]()
+	n2.AddMany(0, 1000, math.MaxUint32)
+	AllTrue(t, n1.IsEq(n2), n2.IsEq(&n1))
+
+	//Zero value sets as arguments
+	var empty nset.NSet[uint32]
+	AllTrue(t, !n2.HasIntersection(&empty), n2.IntersectionLen(&nset.NSet[uint32]{}) == 0, nset.UnionSets(&nset.NSet[uint32]{}, n2).IsEq(n2))
+
+	//Embedded in a struct
+	type user struct {
+		Name   string
+		Groups nset.NSet[uint16]
+	}
+
+	u := user{Name: "a"}
+	u.Groups.Add(math.MaxUint16)
+	AllTrue(t, u.Groups.Contains(math.MaxUint16), !u.Groups.Contains(0))
+
+	//Decoders that only see exported fields
+	jsonSet := nset.NewNSet[uint32]()
+	jsonSet.AddMany(0, 1000, 1<<31)
+	data, err := json.Marshal(jsonSet)
+	AllTrue(t, err == nil)
+
+	var n3 nset.NSet[uint32]
+	AllTrue(t, json.Unmarshal(data, &n3) == nil)
+	AllTrue(t, n3.IsEq(jsonSet), n3.ContainsAll(0, 1000, 1<<31), !n3.Contains(1))
+
+	n4 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(2))
+	n4.AddMany(7, 1<<31)
+	data, err = json.Marshal(n4)
+	AllTrue(t, err == nil)
+
+	var n5 nset.NSet[uint32]
+	AllTrue(t, json.Unmarshal(data, &n5) == nil)
+	IsEq(t, 4, len(n5.Buckets))
+	AllTrue(t, n5.ContainsAll(7, 1<<31), !n5.Contains(6), n5.IsEq(n4))
+
+	n5.Add(6)
+	IsEq(t, 4, len(n5.Buckets))
+	AllTrue(t, n5.Contains(6), n5.BucketIndexingBits() == 2)
+
+	//Sets filled only through the exported fields (e.g. by other decoders) get their layout on the first Add, Remove or Contains
+	n6 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(8))
+	n6.AddMany(7, 1<<24, math.MaxUint32)
+
+	var n7 nset.NSet[uint32]
+	n7.Buckets, n7.StorageUnitCount = n6.Copy().Buckets, n6.StorageUnitCount
+	AllTrue(t, n7.Contains(1<<24), !n7.Contains(1<<24+1))
+
+	var n8 nset.NSet[uint32]
+	n8.Buckets, n8.StorageUnitCount = n6.Copy().Buckets, n6.StorageUnitCount
+	n8.Add(2 << 24)
+	AllTrue(t, n8.Contains(2<<24), n8.Len() == 4, n8.BucketIndexingBits() == 8)
+
+	var n9 nset.NSet[uint32]
+	n9.Buckets, n9.StorageUnitCount = n6.Copy().Buckets, n6.StorageUnitCount
+	n9.Remove(1 << 24)
+	AllTrue(t, !n9.Contains(1<<24), n9.Len() == 2)
+}
+
+func TestNSetIntervals(t *testing.T) {
+
+	bucketSize := uint32(1 << (32 - nset.BucketIndexingBits))
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 1, 2, 5, 63, 64, 65, 127, 128, 1000)
+	n1.Union(nset.FromRange(bucketSize-100, bucketSize+100))
+	n1.Union(nset.FromRange[uint32](math.MaxUint32-200, math.MaxUint32))
+
+	intervals := n1.Intervals()
+	expected := [][2]uint32{{0, 2}, {5, 5}, {63, 65}, {127, 128}, {1000, 1000}, {bucketSize - 100, bucketSize + 100}, {math.MaxUint32 - 200, math.MaxUint32}}
+	IsEq(t, len(expected), len(intervals))
+	for i := 0; i < len(expected) && i < len(intervals); i++ {
+		IsEq(t, expected[i], intervals[i])
+	}
+
+	AllTrue(t, nset.FromIntervals(intervals).IsEq(n1))
+
+	//Stopping early
+	count := 0
+	n1.IntervalsIter(func(start, end uint32) bool {
+		count++
+		return count < 3
+	})
+	IsEq(t, 3, count)
+
+	//Full sets are a single interval, including across buckets
+	IsEq(t, [][2]uint8{{0, math.MaxUint8}}[0], nset.FromRange[uint8](0, math.MaxUint8).Intervals()[0])
+	IsEq(t, 1, len(nset.FromRange[uint16](0, math.MaxUint16).Intervals()))
+	IsEq(t, 1, len(nset.FromIntervals([][2]uint16{{10, 300}, {0, 9}, {5, 20}, {400, 300}}).Intervals()))
+
+	n2 := nset.FromIntervals([][2]uint8{{3, 3}, {10, 20}, {100, 200}})
+	IsEq(t, 3, len(n2.Intervals()))
+	IsEq(t, [2]uint8{100, 200}, n2.Intervals()[2])
+
+	IsEq(t, 0, len(nset.NewNSet[uint32]().Intervals()))
+}
+
+func TestNSetComplement(t *testing.T) {
+
+	bucketSize := uint32(1 << (32 - nset.BucketIndexingBits))
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 2, 63, 64, 100, bucketSize+5, math.MaxUint32)
+
+	c1 := n1.GetComplement(0, 200)
+	IsEq(t, 201-5, c1.Len())
+	AllTrue(t, c1.ContainsAll(1, 3, 62, 65, 99, 101, 200), !c1.ContainsAny(0, 2, 63, 64, 100, 201))
+
+	c2 := n1.GetComplement(bucketSize, bucketSize+10)
+	IsEq(t, [][2]uint32{{bucketSize, bucketSize + 4}, {bucketSize + 6, bucketSize + 10}}[1], c2.Intervals()[1])
+	IsEq(t, 10, c2.Len())
+	IsEq(t, 0, n1.GetComplement(10, 5).Len())
+
+	//In place within [0, max]
+	n1.Complement(bucketSize + 7)
+	IsEq(t, int(bucketSize+8-6), n1.Len())
+	AllTrue(t, n1.ContainsAll(1, 3, 65, bucketSize-1, bucketSize, bucketSize+4, bucketSize+6, bucketSize+7))
+	AllTrue(t, !n1.ContainsAny(0, 2, 63, 64, 100, bucketSize+5, bucketSize+8, math.MaxUint32))
+
+	//Complementing twice gives back the values up to max
+	n1.Complement(bucketSize + 7)
+	n2 := nset.NewNSet[uint32]()
+	n2.AddMany(0, 2, 63, 64, 100, bucketSize+5)
+	AllTrue(t, n1.IsEq(n2))
+
+	//Small types, where buckets are smaller than a storage unit
+	n3 := nset.FromSlice([]uint8{0, 5, 200, 255})
+	n3.Complement(math.MaxUint8)
+	IsEq(t, 252, n3.Len())
+	AllTrue(t, !n3.ContainsAny(0, 5, 200, 255), n3.ContainsAll(1, 4, 6, 199, 201, 254))
+	AllTrue(t, n3.IsEq(nset.FromSlice([]uint8{0, 5, 200, 255}).GetComplement(0, math.MaxUint8)))
+
+	n4 := nset.NewNSet[uint8]()
+	n4.Complement(10)
+	AllTrue(t, n4.IsEq(nset.FromRange[uint8](0, 10)))
+}
+
+func TestNSetRank(t *testing.T) {
+
+	bucketSize := uint32(1 << (32 - nset.BucketIndexingBits))
+
+	n1 := nset.NewNSet[uint32]()
+	IsEq(t, 0, n1.Rank(math.MaxUint32))
+
+	n1.AddMany(0, 5, 64, 1000, bucketSize, math.MaxUint32)
+	IsEq(t, 1, n1.Rank(0))
+	IsEq(t, 1, n1.Rank(4))
+	IsEq(t, 2, n1.Rank(5))
+	IsEq(t, 3, n1.Rank(64))
+	IsEq(t, 4, n1.Rank(bucketSize-1))
+	IsEq(t, 5, n1.Rank(bucketSize))
+	IsEq(t, 5, n1.Rank(math.MaxUint32-1))
+	IsEq(t, 6, n1.Rank(math.MaxUint32))
+
+	n2 := nset.FromRange[uint8](10, 200)
+	for x := 0; x <= math.MaxUint8; x++ {
+
+		expected := 0
+		if x >= 10 {
+			expected = x - 9
+		}
+
+		if x > 200 {
+			expected = 191
+		}
+
+		IsEq(t, expected, n2.Rank(uint8(x)))
+	}
+}
+
 func TestNSetFullRange(t *testing.T) {
 
 	if fullRangeNSet == nil {
@@ -160,6 +619,64 @@ func BenchmarkNSetAdd(b *testing.B) {
 	}
 }
 
+func sortedBenchValues() []uint32 {
+
+	values := make([]uint32, 100_000)
+	for i := 0; i < len(values); i++ {
+		values[i] = uint32(i * 3)
+	}
+
+	return values
+}
+
+func BenchmarkNSetAddLoopSorted(b *testing.B) {
+
+	values := sortedBenchValues()
+	for i := 0; i < b.N; i++ {
+
+		n := nset.NewNSet[uint32]()
+		for j := 0; j < len(values); j++ {
+			n.Add(values[j])
+		}
+	}
+}
+
+func BenchmarkNSetAddManySorted(b *testing.B) {
+
+	values := sortedBenchValues()
+	for i := 0; i < b.N; i++ {
+
+		n := nset.NewNSet[uint32]()
+		n.AddMany(values...)
+	}
+}
+
+func BenchmarkNSetAddSorted(b *testing.B) {
+
+	values := sortedBenchValues()
+	for i := 0; i < b.N; i++ {
+
+		n := nset.NewNSet[uint32]()
+		n.AddSorted(values)
+	}
+}
+
+func BenchmarkNSetAddManyRand(b *testing.B) {
+
+	rand.Seed(RandSeed)
+	values := make([]uint32, 100_000)
+	for i := 0; i < len(values); i++ {
+		values[i] = rand.Uint32() % maxBenchSize
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+
+		n := nset.NewNSet[uint32]()
+		n.AddMany(values...)
+	}
+}
+
 func BenchmarkMapAdd(b *testing.B) {
 
 	hMap := map[uint32]struct{}{}
@@ -326,6 +843,73 @@ func BenchmarkMapContainsRand(b *testing.B) {
 	dump = found
 }
 
+func BenchmarkNSetContainsManyRand(b *testing.B) {
+
+	//Init
+	b.StopTimer()
+	n := nset.NewNSet[uint32]()
+
+	for i := uint32(0); i < maxBenchSize; i++ {
+		n.Add(i)
+	}
+
+	rand.Seed(RandSeed)
+	probes := make([]uint32, 1024)
+	for i := 0; i < len(probes); i++ {
+		probes[i] = rand.Uint32() % maxBenchSize
+	}
+	results := make([]bool, len(probes))
+	b.StartTimer()
+
+	//Work
+	for i := 0; i < b.N; i++ {
+		n.ContainsMany(probes, results)
+	}
+}
+
+//newContainsManySpreadBench returns a set much bigger than the CPU cache, and probes spread over all of it.
+//BenchmarkNSetContainsLoopSpread is the baseline that BenchmarkNSetContainsManySpread is compared against
+func newContainsManySpreadBench(b *testing.B, probeCount int) (*nset.NSet[uint32], []uint32) {
+
+	b.StopTimer()
+	defer b.StartTimer()
+
+	n := nset.NewNSet[uint32]()
+	rand.Seed(RandSeed)
+	for i := 0; i < maxBenchSize; i++ {
+		n.Add(rand.Uint32())
+	}
+
+	probes := make([]uint32, probeCount)
+	for i := 0; i < len(probes); i++ {
+		probes[i] = rand.Uint32()
+	}
+
+	return n, probes
+}
+
+func BenchmarkNSetContainsManySpread(b *testing.B) {
+
+	n, probes := newContainsManySpreadBench(b, 1<<20)
+	results := make([]bool, len(probes))
+
+	for i := 0; i < b.N; i++ {
+		n.ContainsMany(probes, results)
+	}
+}
+
+func BenchmarkNSetContainsLoopSpread(b *testing.B) {
+
+	n, probes := newContainsManySpreadBench(b, 1<<20)
+	results := make([]bool, len(probes))
+
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < len(probes); j++ {
+			results[j] = n.Contains(probes[j])
+		}
+	}
+}
+
 func BenchmarkNSetDelete(b *testing.B) {
 
 	//Init
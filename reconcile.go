@@ -0,0 +1,270 @@
+package nset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//ErrReconcileMismatch is returned (wrapped) by Reconcile when the two sets have a different element type or bucket layout
+var ErrReconcileMismatch = errors.New("nset: reconcile peer set doesn't match")
+
+const (
+	reconcileMagic   = "NSRC"
+	reconcileVersion = 1
+
+	//reconcileLeafStorageUnits is the biggest range whose storage units are sent instead of being split further
+	reconcileLeafStorageUnits = 64
+	//reconcileFanout is the number of parts a differing range is split into in each round
+	reconcileFanout = 16
+)
+
+//reconcileRange is the storage units [start, end) of a bucket
+type reconcileRange struct {
+	bucket     int
+	start, end uint32
+}
+
+//Reconcile makes n and the set on the other side of rw equal to their union, sending data proportional to how much
+//the sets differ rather than to their size. Exactly one of the two sides must be the initiator.
+//
+//The sides first exchange a hash of each bucket. Buckets with different hashes are split into ranges of storage units,
+//whose hashes are exchanged in the next round, and so on, until ranges are small enough to send their storage units.
+//Both sets must have the same element type and bucket layout.
+func Reconcile[T IntsIf](rw io.ReadWriter, n *NSet[T], initiator bool) error {
+
+	n.lazyInit()
+
+	r := &reconcileConn{
+		r:         bufio.NewReader(rw),
+		w:         bufio.NewWriter(rw),
+		initiator: initiator,
+		buf:       make([]byte, 8),
+	}
+
+	//Header
+	header := make([]byte, binaryHeaderSize)
+	copy(header, reconcileMagic)
+	header[4] = reconcileVersion
+	header[5] = typeBitsOf[T]()
+	header[6] = n.BucketIndexingBits()
+
+	peerHeader, err := r.exchange(func() { r.w.Write(header) }, binaryHeaderSize)
+	if err != nil {
+		return err
+	}
+
+	if string(peerHeader[:5]) != string(header[:5]) {
+		return fmt.Errorf("%w: peer isn't speaking the same reconcile protocol", ErrReconcileMismatch)
+	}
+
+	if peerHeader[5] != header[5] || peerHeader[6] != header[6] {
+		return fmt.Errorf("%w: peer set is a %d-bit type with %d bucket indexing bits, but this set is a %d-bit type with %d",
+			ErrReconcileMismatch, peerHeader[5], peerHeader[6], header[5], header[6])
+	}
+
+	//First round has the used storage unit count and hash of each bucket
+	peerBuckets, err := r.exchange(func() {
+		for i := 0; i < len(n.Buckets); i++ {
+			b := &n.Buckets[i]
+			r.writeUint32(b.usedStorageUnitCount())
+			r.writeUint64(n.rangeHash(reconcileRange{bucket: i, start: 0, end: b.StorageUnitCount}))
+		}
+	}, len(n.Buckets)*12)
+	if err != nil {
+		return err
+	}
+
+	ranges := make([]reconcileRange, 0)
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		peerCount := binary.LittleEndian.Uint32(peerBuckets[i*12:])
+		peerHash := binary.LittleEndian.Uint64(peerBuckets[i*12+4:])
+		if peerHash == n.rangeHash(reconcileRange{bucket: i, start: 0, end: b.StorageUnitCount}) {
+			continue
+		}
+
+		if uint64(peerCount) > maxBucketStorageUnitCount(header[5], header[6]) {
+			return fmt.Errorf("%w: peer bucket %d has too many storage units", ErrInvalidBinaryData, i)
+		}
+
+		end := b.usedStorageUnitCount()
+		if peerCount > end {
+			end = peerCount
+		}
+
+		ranges = append(ranges, reconcileRange{bucket: i, start: 0, end: end})
+	}
+
+	//Each round both sides send either the storage units of a range or the hashes of its parts. The two sides
+	//always have the same list of ranges, so only the hashes and storage units need to be sent
+	for len(ranges) > 0 {
+
+		messageSize := 0
+		for _, rng := range ranges {
+			if rng.end-rng.start <= reconcileLeafStorageUnits {
+				messageSize += int(rng.end-rng.start) * 8
+			} else {
+				messageSize += len(splitReconcileRange(rng)) * 8
+			}
+		}
+
+		peerMessage, err := r.exchange(func() {
+			for _, rng := range ranges {
+
+				if rng.end-rng.start <= reconcileLeafStorageUnits {
+					b := &n.Buckets[rng.bucket]
+					for j := rng.start; j < rng.end; j++ {
+						if j < b.StorageUnitCount {
+							r.writeUint64(uint64(b.Data[j]))
+						} else {
+							r.writeUint64(0)
+						}
+					}
+					continue
+				}
+
+				for _, part := range splitReconcileRange(rng) {
+					r.writeUint64(n.rangeHash(part))
+				}
+			}
+		}, messageSize)
+		if err != nil {
+			return err
+		}
+
+		nextRanges := make([]reconcileRange, 0)
+		for _, rng := range ranges {
+
+			if rng.end-rng.start <= reconcileLeafStorageUnits {
+
+				b := &n.Buckets[rng.bucket]
+				for j := rng.start; j < rng.end; j++ {
+
+					peerStorageUnit := StorageType(binary.LittleEndian.Uint64(peerMessage))
+					peerMessage = peerMessage[8:]
+					if peerStorageUnit == 0 {
+						continue
+					}
+
+					n.growToFit(b, j)
+					n.setStorageUnit(BucketType(rng.bucket), j, b.Data[j]|peerStorageUnit)
+				}
+				continue
+			}
+
+			for _, part := range splitReconcileRange(rng) {
+
+				peerHash := binary.LittleEndian.Uint64(peerMessage)
+				peerMessage = peerMessage[8:]
+				if peerHash != n.rangeHash(part) {
+					nextRanges = append(nextRanges, part)
+				}
+			}
+		}
+
+		ranges = nextRanges
+	}
+
+	return nil
+}
+
+//splitReconcileRange splits rng into up to reconcileFanout parts of about the same size
+func splitReconcileRange(rng reconcileRange) []reconcileRange {
+
+	partSize := (rng.end - rng.start + reconcileFanout - 1) / reconcileFanout
+	parts := make([]reconcileRange, 0, reconcileFanout)
+	for start := rng.start; start < rng.end; start += partSize {
+
+		end := start + partSize
+		if end > rng.end {
+			end = rng.end
+		}
+
+		parts = append(parts, reconcileRange{bucket: rng.bucket, start: start, end: end})
+	}
+
+	return parts
+}
+
+//rangeHash returns a hash of the storage units in rng. Empty storage units don't change the hash, so two sets
+//with the same elements have the same hash no matter how many storage units they have allocated
+func (n *NSet[T]) rangeHash(rng reconcileRange) uint64 {
+
+	b := &n.Buckets[rng.bucket]
+
+	end := rng.end
+	if end > b.StorageUnitCount {
+		end = b.StorageUnitCount
+	}
+
+	h := uint64(0)
+	for j := rng.start; j < end; j++ {
+		if b.Data[j] != 0 {
+			h ^= storageUnitHash(uint64(rng.bucket)<<32|uint64(j), b.Data[j])
+		}
+	}
+
+	return h
+}
+
+//reconcileConn is one side of a Reconcile connection
+type reconcileConn struct {
+	r         *bufio.Reader
+	w         *bufio.Writer
+	initiator bool
+	buf       []byte
+	peerBuf   []byte
+}
+
+func (c *reconcileConn) writeUint32(x uint32) {
+	binary.LittleEndian.PutUint32(c.buf, x)
+	c.w.Write(c.buf[:4])
+}
+
+func (c *reconcileConn) writeUint64(x uint64) {
+	binary.LittleEndian.PutUint64(c.buf, x)
+	c.w.Write(c.buf)
+}
+
+//exchange sends the message written by write and returns the peer's message, which is peerSize bytes.
+//The initiator sends first and the other side reads first, so the two never block writing to each other
+//(e.g. over an unbuffered connection like net.Pipe). The returned slice is only valid until the next exchange
+func (c *reconcileConn) exchange(write func(), peerSize int) ([]byte, error) {
+
+	send := func() error {
+		write()
+		return c.w.Flush()
+	}
+
+	if c.initiator {
+		if err := send(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cap(c.peerBuf) < peerSize {
+		c.peerBuf = make([]byte, peerSize)
+	}
+
+	peerMessage := c.peerBuf[:peerSize]
+	if _, err := io.ReadFull(c.r, peerMessage); err != nil {
+
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, fmt.Errorf("nset: reading from reconcile peer: %w", err)
+	}
+
+	if !c.initiator {
+		if err := send(); err != nil {
+			return nil, err
+		}
+	}
+
+	return peerMessage, nil
+}
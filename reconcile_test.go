@@ -0,0 +1,104 @@
+package nset_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+//countingReadWriter counts the bytes written through it
+type countingReadWriter struct {
+	io.ReadWriter
+	written int
+}
+
+func (c *countingReadWriter) Write(p []byte) (int, error) {
+	n, err := c.ReadWriter.Write(p)
+	c.written += n
+	return n, err
+}
+
+//reconcile runs Reconcile on both sets over a net.Pipe and returns the errors of both sides and the bytes they sent
+func reconcile[T nset.IntsIf](n1, n2 *nset.NSet[T]) (err1, err2 error, bytesSent int) {
+
+	c1, c2 := net.Pipe()
+	rw1 := &countingReadWriter{ReadWriter: c1}
+	rw2 := &countingReadWriter{ReadWriter: c2}
+
+	done := make(chan error)
+	go func() {
+		err := nset.Reconcile(rw2, n2, false)
+		c2.Close()
+		done <- err
+	}()
+
+	err1 = nset.Reconcile(rw1, n1, true)
+	c1.Close()
+	err2 = <-done
+
+	return err1, err2, rw1.written + rw2.written
+}
+
+func TestReconcile(t *testing.T) {
+
+	n1 := nset.FromRange[uint32](0, 10_000_000)
+	n1.AddMany(1<<30, math.MaxUint32)
+
+	n2 := n1.Copy()
+	n1.AddMany(20_000_000, 3<<30)
+	n2.Remove(5)
+	n2.Remove(9_999_999)
+	n2.Add(1 << 31)
+
+	expected := nset.UnionSets(n1, n2)
+	full, err := expected.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	err1, err2, bytesSent := reconcile(n1, n2)
+	AllTrue(t, err1 == nil, err2 == nil)
+	AllTrue(t, n1.IsEq(expected), n2.IsEq(expected))
+	AllTrue(t, bytesSent < len(full)/50)
+
+	//Equal sets only exchange bucket hashes
+	err1, err2, bytesSent = reconcile(n1, n2)
+	AllTrue(t, err1 == nil, err2 == nil, n1.IsEq(expected))
+	AllTrue(t, bytesSent <= 2*(8+128*12))
+
+	//Empty sets and small types
+	s1 := nset.NewNSet[uint8]()
+	s2 := nset.FromSlice([]uint8{0, 7, 255})
+	err1, err2, _ = reconcile(s1, s2)
+	AllTrue(t, err1 == nil, err2 == nil, s1.IsEq(s2), s1.Len() == 3)
+
+	//Random sets with every layout
+	rng := rand.New(rand.NewSource(42))
+	for bits := uint8(0); bits <= nset.MaxBucketIndexingBits; bits++ {
+
+		r1 := nset.NewNSet[uint16](nset.WithBucketIndexingBits(bits))
+		r2 := nset.NewNSet[uint16](nset.WithBucketIndexingBits(bits))
+		for i := 0; i < 2000; i++ {
+			r1.Add(uint16(rng.Intn(math.MaxUint16 + 1)))
+			r2.Add(uint16(rng.Intn(math.MaxUint16 + 1)))
+		}
+
+		expected := nset.UnionSets(r1, r2)
+		err1, err2, _ = reconcile(r1, r2)
+		AllTrue(t, err1 == nil, err2 == nil, r1.IsEq(expected), r2.IsEq(expected))
+	}
+}
+
+func TestReconcileMismatch(t *testing.T) {
+
+	n1 := nset.FromSlice([]uint32{1, 2, 3})
+	n2 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(3))
+	n2.Add(4)
+
+	err1, err2, _ := reconcile(n1, n2)
+	AllTrue(t, errors.Is(err1, nset.ErrReconcileMismatch), errors.Is(err2, nset.ErrReconcileMismatch))
+	AllTrue(t, !n1.Contains(4), !n2.Contains(1))
+}
@@ -0,0 +1,288 @@
+package nset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+//ErrInvalidRoaringData is returned (wrapped) by ReadRoaring when the data isn't a valid Roaring bitmap
+var ErrInvalidRoaringData = errors.New("nset: invalid roaring data")
+
+//Constants from the Roaring portable serialization format spec (https://github.com/RoaringBitmap/RoaringFormatSpec)
+const (
+	roaringSerialCookieNoRunContainer = 12346
+	roaringSerialCookie               = 12347
+	roaringNoOffsetThreshold          = 4
+	//roaringMaxArrayContainerSize is the biggest cardinality stored as an array container. Bigger containers are bitmaps
+	roaringMaxArrayContainerSize = 4096
+	//roaringContainerStorageUnits is the number of storage units that hold the 2^16 values of a container
+	roaringContainerStorageUnits = (1 << 16) / StorageTypeBits
+)
+
+type roaringContainer struct {
+	key         uint16
+	cardinality int
+	//storageUnits are the 1024 storage units of the bucket that hold the values of this container.
+	//If the bucket doesn't have all of them the missing ones are empty.
+	storageUnits []StorageType
+}
+
+//WriteRoaring writes the set in the Roaring bitmap portable serialization format, which can be read by
+//Roaring implementations in other languages (e.g. Java, C and Python).
+//
+//Values are split into containers by their top 16 bits. Containers with up to 4096 values are written as sorted
+//lists of their bottom 16 bits and bigger containers as bitmaps. Run containers are not written.
+func WriteRoaring(w io.Writer, n *NSet[uint32]) error {
+
+	n.lazyInit()
+
+	//Buckets hold at least 2^24 values, so every container is within a single bucket
+	containers := make([]roaringContainer, 0)
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		bucketFirstValue := uint32(i) << n.shiftAmount()
+		for j := 0; j < len(b.Data); j += roaringContainerStorageUnits {
+
+			end := j + roaringContainerStorageUnits
+			if end > len(b.Data) {
+				end = len(b.Data)
+			}
+
+			cardinality := storageUnitsOnesCount(b.Data[j:end])
+			if cardinality == 0 {
+				continue
+			}
+
+			containers = append(containers, roaringContainer{
+				key:          uint16((bucketFirstValue + uint32(j*StorageTypeBits)) >> 16),
+				cardinality:  cardinality,
+				storageUnits: b.Data[j:end],
+			})
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 8)
+	writeUint16 := func(x uint16) {
+		binary.LittleEndian.PutUint16(buf, x)
+		bw.Write(buf[:2])
+	}
+
+	writeUint32 := func(x uint32) {
+		binary.LittleEndian.PutUint32(buf, x)
+		bw.Write(buf[:4])
+	}
+
+	//Cookie and descriptive header
+	writeUint32(roaringSerialCookieNoRunContainer)
+	writeUint32(uint32(len(containers)))
+	for i := 0; i < len(containers); i++ {
+		writeUint16(containers[i].key)
+		writeUint16(uint16(containers[i].cardinality - 1))
+	}
+
+	//Offset header, which has the offset of each container from the start of the data
+	offset := uint32(8 + len(containers)*8)
+	for i := 0; i < len(containers); i++ {
+
+		writeUint32(offset)
+		if containers[i].cardinality <= roaringMaxArrayContainerSize {
+			offset += uint32(containers[i].cardinality) * 2
+		} else {
+			offset += roaringContainerStorageUnits * 8
+		}
+	}
+
+	//Containers
+	for i := 0; i < len(containers); i++ {
+
+		c := &containers[i]
+		if c.cardinality > roaringMaxArrayContainerSize {
+
+			for j := 0; j < roaringContainerStorageUnits; j++ {
+
+				storageUnit := StorageType(0)
+				if j < len(c.storageUnits) {
+					storageUnit = c.storageUnits[j]
+				}
+
+				binary.LittleEndian.PutUint64(buf, uint64(storageUnit))
+				bw.Write(buf)
+			}
+
+			continue
+		}
+
+		for j := 0; j < len(c.storageUnits); j++ {
+
+			storageUnit := c.storageUnits[j]
+			for storageUnit != 0 {
+				writeUint16(uint16(j*StorageTypeBits + bits.TrailingZeros64(uint64(storageUnit))))
+				storageUnit &= storageUnit - 1
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+//ReadRoaring reads a set written in the Roaring bitmap portable serialization format (e.g. by WriteRoaring or
+//by Roaring implementations in other languages). All container types, including run containers, are supported.
+//The options are used to create the returned set.
+func ReadRoaring(r io.Reader, options ...Option) (*NSet[uint32], error) {
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, roaringContainerStorageUnits*8)
+	readBytes := func(count int) ([]byte, error) {
+
+		if _, err := io.ReadFull(br, buf[:count]); err != nil {
+
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("%w: data ended early", ErrInvalidRoaringData)
+			}
+
+			return nil, err
+		}
+
+		return buf[:count], nil
+	}
+
+	//Cookie
+	header, err := readBytes(4)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := binary.LittleEndian.Uint32(header)
+
+	var containerCount int
+	var isRunContainer []byte
+	hasOffsets := true
+	switch {
+	case cookie == roaringSerialCookieNoRunContainer:
+
+		header, err = readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+
+		containerCount = int(binary.LittleEndian.Uint32(header))
+		if containerCount > 1<<16 {
+			return nil, fmt.Errorf("%w: too many containers (%d)", ErrInvalidRoaringData, containerCount)
+		}
+
+	case cookie&0xFFFF == roaringSerialCookie:
+
+		containerCount = int(cookie>>16) + 1
+		hasOffsets = containerCount >= roaringNoOffsetThreshold
+
+		header, err = readBytes((containerCount + 7) / 8)
+		if err != nil {
+			return nil, err
+		}
+
+		isRunContainer = append([]byte{}, header...)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown cookie %d", ErrInvalidRoaringData, cookie)
+	}
+
+	//Descriptive header
+	keys := make([]uint16, containerCount)
+	cardinalities := make([]int, containerCount)
+	for i := 0; i < containerCount; i++ {
+
+		header, err = readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i] = binary.LittleEndian.Uint16(header)
+		cardinalities[i] = int(binary.LittleEndian.Uint16(header[2:])) + 1
+		if i > 0 && keys[i] <= keys[i-1] {
+			return nil, fmt.Errorf("%w: container keys are not sorted", ErrInvalidRoaringData)
+		}
+	}
+
+	//Containers are stored in order, so the offsets aren't needed
+	if hasOffsets {
+		if _, err = readBytes(containerCount * 4); err != nil {
+			return nil, err
+		}
+	}
+
+	n := NewNSet[uint32](options...)
+	for i := 0; i < containerCount; i++ {
+
+		containerFirstValue := uint32(keys[i]) << 16
+		isRun := isRunContainer != nil && isRunContainer[i/8]&(1<<(i%8)) != 0
+		switch {
+		case isRun:
+
+			header, err = readBytes(2)
+			if err != nil {
+				return nil, err
+			}
+
+			runCount := int(binary.LittleEndian.Uint16(header))
+			runs, err := readBytes(runCount * 4)
+			if err != nil {
+				return nil, err
+			}
+
+			for j := 0; j < runCount; j++ {
+
+				start := uint32(binary.LittleEndian.Uint16(runs[j*4:]))
+				length := uint32(binary.LittleEndian.Uint16(runs[j*4+2:]))
+				if start+length > 0xFFFF {
+					return nil, fmt.Errorf("%w: run goes past the end of its container", ErrInvalidRoaringData)
+				}
+
+				n.addRange(containerFirstValue+start, containerFirstValue+start+length)
+			}
+
+		case cardinalities[i] <= roaringMaxArrayContainerSize:
+
+			values, err := readBytes(cardinalities[i] * 2)
+			if err != nil {
+				return nil, err
+			}
+
+			for j := 0; j < cardinalities[i]; j++ {
+				x := containerFirstValue | uint32(binary.LittleEndian.Uint16(values[j*2:]))
+				b := n.bucketFromValue(x)
+				unitIndex := n.storageUnitIndex(x)
+				n.growToFit(b, unitIndex)
+				b.Data[unitIndex] |= n.bitMask(x)
+			}
+
+		default:
+
+			storageUnits, err := readBytes(roaringContainerStorageUnits * 8)
+			if err != nil {
+				return nil, err
+			}
+
+			b := n.bucketFromValue(containerFirstValue)
+			firstUnitIndex := n.storageUnitIndex(containerFirstValue)
+			for j := roaringContainerStorageUnits - 1; j >= 0; j-- {
+
+				storageUnit := StorageType(binary.LittleEndian.Uint64(storageUnits[j*8:]))
+				if storageUnit == 0 {
+					continue
+				}
+
+				//Going from the end means the bucket only grows once
+				n.growToFit(b, firstUnitIndex+uint32(j))
+				b.Data[firstUnitIndex+uint32(j)] = storageUnit
+			}
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package nset_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+//roaringSpecSet returns the set stored in the test files of the Roaring format spec (testdata/roaring)
+func roaringSpecSet() *nset.NSet[uint32] {
+
+	n := nset.NewNSet[uint32]()
+	for k := uint32(0); k < 100000; k += 1000 {
+		n.Add(k)
+	}
+
+	for k := uint32(100000); k < 200000; k++ {
+		n.Add(3 * k)
+	}
+
+	for k := uint32(700000); k < 800000; k++ {
+		n.Add(k)
+	}
+
+	return n
+}
+
+func TestRoaringGoldenFiles(t *testing.T) {
+
+	expected := roaringSpecSet()
+	for _, fileName := range []string{"testdata/roaring/bitmapwithoutruns.bin", "testdata/roaring/bitmapwithruns.bin"} {
+
+		data, err := os.ReadFile(fileName)
+		AllTrue(t, err == nil)
+
+		n, err := nset.ReadRoaring(bytes.NewReader(data))
+		AllTrue(t, err == nil)
+		IsEq(t, expected.Len(), n.Len())
+		AllTrue(t, n.IsEq(expected))
+
+		//Truncated data
+		_, err = nset.ReadRoaring(bytes.NewReader(data[:len(data)-1]))
+		AllTrue(t, errors.Is(err, nset.ErrInvalidRoaringData))
+	}
+
+	//Our output matches other implementations byte for byte
+	data, err := os.ReadFile("testdata/roaring/bitmapwithoutruns.bin")
+	AllTrue(t, err == nil)
+
+	buf := &bytes.Buffer{}
+	AllTrue(t, nset.WriteRoaring(buf, expected) == nil)
+	AllTrue(t, bytes.Equal(data, buf.Bytes()))
+}
+
+func TestRoaringRoundTrip(t *testing.T) {
+
+	sets := []*nset.NSet[uint32]{
+		nset.NewNSet[uint32](),
+		nset.FromSlice([]uint32{0, 1, 65535, 65536, 1 << 24, math.MaxUint32}),
+		nset.FromRange[uint32](1000, 200_000),
+		nset.FromRange[uint32](math.MaxUint32-70_000, math.MaxUint32),
+	}
+
+	//Different layouts write the same data
+	withLayout := nset.NewNSet[uint32](nset.WithBucketIndexingBits(2))
+	withLayout.AddMany(3, 5, 1<<20, 1<<31)
+	withLayout.UnionWith(nset.FromRange[uint32](1<<30, 1<<30+5000))
+	sets = append(sets, withLayout)
+
+	for _, n := range sets {
+
+		buf := &bytes.Buffer{}
+		AllTrue(t, nset.WriteRoaring(buf, n) == nil)
+
+		n2, err := nset.ReadRoaring(bytes.NewReader(buf.Bytes()), nset.WithBucketIndexingBits(4))
+		AllTrue(t, err == nil)
+		IsEq(t, 16, len(n2.Buckets))
+		AllTrue(t, n2.IsEq(n), n.IsEq(n2))
+	}
+
+	_, err := nset.ReadRoaring(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+	AllTrue(t, errors.Is(err, nset.ErrInvalidRoaringData))
+}
@@ -0,0 +1,76 @@
+package nset
+
+var (
+	_ Set[uint8]  = &NSet[uint8]{}
+	_ Set[uint16] = &NSet[uint16]{}
+	_ Set[uint32] = &NSet[uint32]{}
+	_ Set[uint8]  = &Set8{}
+	_ Set[uint16] = &Set16{}
+	_ Set[uint64] = MapSet[uint64]{}
+)
+
+//Set has the operations shared by NSet, Set8, Set16 and MapSet, so code can work with any of them
+type Set[T comparable] interface {
+	Add(x T)
+	Remove(x T)
+	Contains(x T) bool
+	Len() int
+	//UnionWith adds all the elements of otherSet to this set
+	UnionWith(otherSet Set[T])
+	//IntersectWith removes all the elements that are not in otherSet from this set
+	IntersectWith(otherSet Set[T])
+	//ForEach calls f on all elements, and stops early if f returns false.
+	//NSet, Set8 and Set16 go over elements in ascending order, while MapSet has no order.
+	ForEach(f func(x T) bool)
+}
+
+//MapSet is a Set backed by a Go map. It is much slower and bigger than NSet, but works with any comparable type
+//(e.g. uint64 values too big for NSet), and is simple enough to be used as a reference when testing other sets.
+type MapSet[T comparable] map[T]struct{}
+
+func NewMapSet[T comparable]() MapSet[T] {
+	return MapSet[T]{}
+}
+
+func (m MapSet[T]) Add(x T) {
+	m[x] = struct{}{}
+}
+
+func (m MapSet[T]) Remove(x T) {
+	delete(m, x)
+}
+
+func (m MapSet[T]) Contains(x T) bool {
+	_, ok := m[x]
+	return ok
+}
+
+func (m MapSet[T]) Len() int {
+	return len(m)
+}
+
+func (m MapSet[T]) UnionWith(otherSet Set[T]) {
+
+	otherSet.ForEach(func(x T) bool {
+		m[x] = struct{}{}
+		return true
+	})
+}
+
+func (m MapSet[T]) IntersectWith(otherSet Set[T]) {
+
+	for x := range m {
+		if !otherSet.Contains(x) {
+			delete(m, x)
+		}
+	}
+}
+
+func (m MapSet[T]) ForEach(f func(x T) bool) {
+
+	for x := range m {
+		if !f(x) {
+			return
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package nset
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+//Set16 is a set of all uint16 values stored as a fixed size array of 1024 storage units (8 KB).
+//
+//Like Set8, Set16 is a value type: the zero value is an empty set ready for use, assigning copies the set,
+//and two sets can be compared with '=='. At 8 KB, pass it around as a pointer unless a copy is wanted.
+//
+//The only NSet methods it doesn't have are the bucket ones (BucketIndexingBits, GetBucketFromValue and friends, Reserve
+//and Apply). Its encodings are the ones of an NSet[uint16] with the default layout, and can be read by either type.
+type Set16 [1024]StorageType
+
+var _ fmt.Stringer = &Set16{}
+
+func (s *Set16) Add(x uint16) {
+	s[x/StorageTypeBits] |= 1 << (x % StorageTypeBits)
+}
+
+func (s *Set16) AddMany(values ...uint16) {
+
+	for i := 0; i < len(values); i++ {
+		s.Add(values[i])
+	}
+}
+
+//AddSorted adds all values to the set. It exists to match NSet, as adding is equally fast for sorted and unsorted values
+func (s *Set16) AddSorted(values []uint16) {
+	s.AddMany(values...)
+}
+
+func (s *Set16) Remove(x uint16) {
+	s[x/StorageTypeBits] &^= 1 << (x % StorageTypeBits)
+}
+
+func (s *Set16) Contains(x uint16) bool {
+	return s[x/StorageTypeBits]&(1<<(x%StorageTypeBits)) != 0
+}
+
+func (s *Set16) ContainsAny(values ...uint16) bool {
+	return storageUnitsContainsAny(s[:], values)
+}
+
+func (s *Set16) ContainsAll(values ...uint16) bool {
+	return storageUnitsContainsAll(s[:], values)
+}
+
+//ContainsMany sets out[i] to whether values[i] is in the set. out must be at least as long as values.
+func (s *Set16) ContainsMany(values []uint16, out []bool) {
+	storageUnitsContainsMany(s[:], values, out)
+}
+
+//ContainsManyBitset is like ContainsMany but writes the results as a packed bitset, where bit 'i%64' of out[i/64]
+//is set if values[i] is in the set and cleared otherwise. out must have at least (len(values)+63)/64 storage units.
+func (s *Set16) ContainsManyBitset(values []uint16, out []StorageType) {
+	storageUnitsContainsManyBitset(s[:], values, out)
+}
+
+//ContainsManyInto adds to outSet every value in values that is in this set
+func (s *Set16) ContainsManyInto(values []uint16, outSet *Set16) {
+	storageUnitsContainsManyInto(s[:], values, outSet[:])
+}
+
+//Len returns the number of elements in the set
+func (s *Set16) Len() int {
+	return storageUnitsOnesCount(s[:])
+}
+
+//Rank returns the number of elements in the set that are smaller than or equal to x
+func (s *Set16) Rank(x uint16) int {
+	return storageUnitsRank(s[:], x)
+}
+
+func (s *Set16) Union(otherSet *Set16) {
+	storageUnitsUnion(s[:], otherSet[:])
+}
+
+func (s *Set16) GetIntersection(otherSet *Set16) *Set16 {
+
+	outSet := *s
+	storageUnitsIntersect(outSet[:], otherSet[:])
+	return &outSet
+}
+
+//GetAllElements returns all the elements of the set in ascending order
+func (s *Set16) GetAllElements() []uint16 {
+	return storageUnitsElements[uint16](s[:])
+}
+
+func (s *Set16) IsEq(otherSet *Set16) bool {
+	return *s == *otherSet
+}
+
+func (s *Set16) HasIntersection(otherSet *Set16) bool {
+	return storageUnitsHasIntersection(s[:], otherSet[:])
+}
+
+//IntersectionLen returns the number of elements in the intersection of both sets without creating the intersection set
+func (s *Set16) IntersectionLen(otherSet *Set16) int {
+	return storageUnitsIntersectionLen(s[:], otherSet[:])
+}
+
+//UnionLen returns the number of elements in the union of both sets without creating the union set
+func (s *Set16) UnionLen(otherSet *Set16) int {
+	return storageUnitsUnionLen(s[:], otherSet[:])
+}
+
+//DifferenceLen returns the number of elements that are in this set but not in otherSet, without creating the difference set
+func (s *Set16) DifferenceLen(otherSet *Set16) int {
+	return storageUnitsDifferenceLen(s[:], otherSet[:])
+}
+
+//JaccardSimilarity returns the size of the intersection divided by the size of the union of both sets.
+//Two empty sets are considered equal and so return 1.
+func (s *Set16) JaccardSimilarity(otherSet *Set16) float64 {
+	return storageUnitsJaccardSimilarity(s[:], otherSet[:])
+}
+
+//UnionWith adds all the elements of otherSet to this set. If otherSet is a Set16 this is the same as Union
+func (s *Set16) UnionWith(otherSet Set[uint16]) {
+
+	if o, ok := otherSet.(*Set16); ok {
+		s.Union(o)
+		return
+	}
+
+	storageUnitsUnionWith(s[:], otherSet)
+}
+
+//IntersectWith removes all the elements that are not in otherSet from this set
+func (s *Set16) IntersectWith(otherSet Set[uint16]) {
+
+	if o, ok := otherSet.(*Set16); ok {
+		storageUnitsIntersect(s[:], o[:])
+		return
+	}
+
+	storageUnitsIntersectWith(s[:], otherSet)
+}
+
+//ForEach calls f on all elements in ascending order, and stops early if f returns false
+func (s *Set16) ForEach(f func(x uint16) bool) {
+	storageUnitsForEach(s[:], f)
+}
+
+//ToMap returns a map that has all the elements of the set as keys
+func (s *Set16) ToMap() map[uint16]struct{} {
+	return storageUnitsToMap[uint16](s[:])
+}
+
+//Complement changes the set to have all values in [0, max] that were not in it, and removes all values bigger than max
+func (s *Set16) Complement(max uint16) {
+	storageUnitsComplement(s[:], max)
+}
+
+//GetComplement returns a new set with all values in [lo, hi] (inclusive of both) that are not in this set.
+//If lo > hi the returned set is empty.
+func (s *Set16) GetComplement(lo, hi uint16) *Set16 {
+
+	outSet := &Set16{}
+	storageUnitsGetComplement(s[:], lo, hi, outSet[:])
+	return outSet
+}
+
+//Intervals returns the elements of the set as a sorted list of [start, end] intervals (inclusive of both),
+//where each interval is the longest run of consecutive values in the set
+func (s *Set16) Intervals() [][2]uint16 {
+	return storageUnitsIntervals[uint16](s[:])
+}
+
+//IntervalsIter is like Intervals but calls f on each interval in ascending order instead of returning a list.
+//It stops early if f returns false.
+func (s *Set16) IntervalsIter(f func(start, end uint16) bool) {
+	storageUnitsIntervalsIter(s[:], f)
+}
+
+//Hash64 returns the same hash as NSet.Hash64 for a set with the same elements.
+//It goes over all 1024 storage units on every call, as there is no cache, which also means it never writes to the set
+func (s *Set16) Hash64() uint64 {
+	return storageUnitsHash64(s[:])
+}
+
+//Fingerprint returns the same fingerprint as NSet.Fingerprint for a set with the same elements
+func (s *Set16) Fingerprint() [32]byte {
+	return storageUnitsFingerprint(s[:])
+}
+
+//Stats returns the memory usage and density of the set, as a single bucket
+func (s *Set16) Stats() Stats {
+	return storageUnitsStats(s[:])
+}
+
+//String returns a string of the storage as bytes separated by spaces. A comma is between each storage unit
+func (s *Set16) String() string {
+	return storageUnitsString(s[:])
+}
+
+func (s *Set16) Copy() *Set16 {
+	newSet := *s
+	return &newSet
+}
+
+//MarshalBinary encodes the set in the binary format of NSet.MarshalBinary
+func (s *Set16) MarshalBinary() ([]byte, error) {
+	return storageUnitsToNSet[uint16](s[:]).MarshalBinary()
+}
+
+//UnmarshalBinary replaces the contents of the set with the ones encoded in data by MarshalBinary of a Set16 or an NSet[uint16].
+//The set is only changed if data is valid.
+func (s *Set16) UnmarshalBinary(data []byte) error {
+	return s.decode(func(n *NSet[uint16]) error { return n.UnmarshalBinary(data) })
+}
+
+func (s *Set16) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+func (s *Set16) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+//WriteCompressed writes the set in the compressed format of NSet.WriteCompressed
+func (s *Set16) WriteCompressed(w io.Writer) error {
+	return storageUnitsToNSet[uint16](s[:]).WriteCompressed(w)
+}
+
+//ReadCompressed replaces the contents of the set with the ones written by WriteCompressed of a Set16 or an NSet[uint16].
+//The set is only changed if the whole set is read without errors.
+func (s *Set16) ReadCompressed(r io.Reader) error {
+	return s.decode(func(n *NSet[uint16]) error { return n.ReadCompressed(r) })
+}
+
+//EncodeString returns the compressed format of the set as URL-safe base64, like NSet.EncodeString
+func (s *Set16) EncodeString() string {
+	return storageUnitsToNSet[uint16](s[:]).EncodeString()
+}
+
+func (s *Set16) DecodeString(str string) error {
+	return s.decode(func(n *NSet[uint16]) error { return n.DecodeString(str) })
+}
+
+//EncodeHexString is like EncodeString but uses lowercase hex
+func (s *Set16) EncodeHexString() string {
+	return storageUnitsToNSet[uint16](s[:]).EncodeHexString()
+}
+
+func (s *Set16) DecodeHexString(str string) error {
+	return s.decode(func(n *NSet[uint16]) error { return n.DecodeHexString(str) })
+}
+
+//Value stores the set in a database like NSet.Value. A nil set is stored as NULL.
+func (s *Set16) Value() (driver.Value, error) {
+
+	if s == nil {
+		return nil, nil
+	}
+
+	return s.MarshalBinary()
+}
+
+//Scan replaces the contents of the set with a value read from a database, in any of the forms NSet.Scan accepts
+func (s *Set16) Scan(src any) error {
+	return s.decode(func(n *NSet[uint16]) error { return n.Scan(src) })
+}
+
+//decode reads the set into an NSet with decodeNSet, and only replaces the contents of the set if that succeeds
+func (s *Set16) decode(decodeNSet func(n *NSet[uint16]) error) error {
+
+	n := &NSet[uint16]{}
+	if err := decodeNSet(n); err != nil {
+		return err
+	}
+
+	storageUnitsFromNSet(s[:], n)
+	return nil
+}
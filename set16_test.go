@@ -0,0 +1,62 @@
+package nset_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/bloeys/nset"
+)
+
+func TestSet16(t *testing.T) {
+
+	IsEq(t, uintptr(8*1024), unsafe.Sizeof(nset.Set16{}))
+
+	s1 := &nset.Set16{}
+	s1.AddMany(0, 1, 63, 64, 1000, math.MaxUint16)
+	s1.Remove(1)
+
+	AllTrue(t, s1.ContainsAll(0, 63, 64, 1000, math.MaxUint16), !s1.ContainsAny(1, 65, 999, math.MaxUint16-1))
+	IsEq(t, 5, s1.Len())
+
+	s2 := *s1
+	AllTrue(t, *s1 == s2)
+
+	s2.Remove(1000)
+	AllTrue(t, *s1 != s2, s1.Contains(1000))
+
+	var s3 nset.Set16
+	s3.AddMany(64, 1000, 30_000)
+	IsEq(t, 2, s1.IntersectionLen(&s3))
+	IsEq(t, 6, s1.UnionLen(&s3))
+	AllTrue(t, s1.GetIntersection(&s3).IsEq(&nset.Set16{1: 1, 15: 1 << (1000 % 64)}))
+
+	//Matches NSet
+	n := nset.NewNSet[uint16]()
+	n.AddMany(0, 63, 64, 1000, math.MaxUint16)
+	AllTrue(t, nset.FromSlice(s1.GetAllElements()).IsEq(n))
+
+	//Complement
+	comp := s1.GetComplement(0, 1000)
+	IsEq(t, 1001-4, comp.Len())
+	AllTrue(t, comp.ContainsAll(1, 62, 65, 999), !comp.ContainsAny(0, 63, 64, 1000, 1001))
+
+	n.Complement(1000)
+	s1.Complement(1000)
+	AllTrue(t, nset.FromSlice(s1.GetAllElements()).IsEq(n), !s1.Contains(math.MaxUint16))
+	IsEq(t, 1001-4, s1.Len())
+
+	full := nset.Set16{}
+	for i := 0; i <= math.MaxUint16; i++ {
+		full.Add(uint16(i))
+	}
+	IsEq(t, math.MaxUint16+1, full.Len())
+	IsEq(t, math.MaxUint16+1, len(full.GetAllElements()))
+}
+
+func TestSet16MatchesNSet(t *testing.T) {
+
+	rand.Seed(RandSeed)
+	testFlatSetAgainstNSet(t, func() flatSet[uint16] { return &nset.Set16{} }, math.MaxUint16)
+}
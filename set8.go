@@ -0,0 +1,281 @@
+package nset
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+//Set8 is a set of all uint8 values stored as a fixed size array of 4 storage units (32 bytes).
+//
+//Unlike NSet, Set8 is a value type: the zero value is an empty set ready for use, assigning copies the set,
+//and two sets can be compared with '=='.
+//
+//It has the methods of NSet, except the ones about buckets (BucketIndexingBits, GetBucketFromValue and friends, Reserve and Apply)
+//as its storage units are not split into buckets. Encodings (e.g. MarshalBinary, WriteCompressed, EncodeString) write
+//the same data as an NSet[uint8] with the default layout, so either can read what the other wrote.
+type Set8 [4]StorageType
+
+var _ fmt.Stringer = &Set8{}
+
+func (s *Set8) Add(x uint8) {
+	s[x/StorageTypeBits] |= 1 << (x % StorageTypeBits)
+}
+
+func (s *Set8) AddMany(values ...uint8) {
+
+	for i := 0; i < len(values); i++ {
+		s.Add(values[i])
+	}
+}
+
+//AddSorted adds all values to the set. It exists to match NSet, as adding is equally fast for sorted and unsorted values
+func (s *Set8) AddSorted(values []uint8) {
+	s.AddMany(values...)
+}
+
+func (s *Set8) Remove(x uint8) {
+	s[x/StorageTypeBits] &^= 1 << (x % StorageTypeBits)
+}
+
+func (s *Set8) Contains(x uint8) bool {
+	return s[x/StorageTypeBits]&(1<<(x%StorageTypeBits)) != 0
+}
+
+func (s *Set8) ContainsAny(values ...uint8) bool {
+	return storageUnitsContainsAny(s[:], values)
+}
+
+func (s *Set8) ContainsAll(values ...uint8) bool {
+	return storageUnitsContainsAll(s[:], values)
+}
+
+//ContainsMany sets out[i] to whether values[i] is in the set. out must be at least as long as values.
+func (s *Set8) ContainsMany(values []uint8, out []bool) {
+	storageUnitsContainsMany(s[:], values, out)
+}
+
+//ContainsManyBitset is like ContainsMany but writes the results as a packed bitset, where bit 'i%64' of out[i/64]
+//is set if values[i] is in the set and cleared otherwise. out must have at least (len(values)+63)/64 storage units.
+func (s *Set8) ContainsManyBitset(values []uint8, out []StorageType) {
+	storageUnitsContainsManyBitset(s[:], values, out)
+}
+
+//ContainsManyInto adds to outSet every value in values that is in this set
+func (s *Set8) ContainsManyInto(values []uint8, outSet *Set8) {
+	storageUnitsContainsManyInto(s[:], values, outSet[:])
+}
+
+//Len returns the number of elements in the set
+func (s *Set8) Len() int {
+	return storageUnitsOnesCount(s[:])
+}
+
+//Rank returns the number of elements in the set that are smaller than or equal to x
+func (s *Set8) Rank(x uint8) int {
+	return storageUnitsRank(s[:], x)
+}
+
+func (s *Set8) Union(otherSet *Set8) {
+	storageUnitsUnion(s[:], otherSet[:])
+}
+
+func (s *Set8) GetIntersection(otherSet *Set8) *Set8 {
+
+	outSet := *s
+	storageUnitsIntersect(outSet[:], otherSet[:])
+	return &outSet
+}
+
+//GetAllElements returns all the elements of the set in ascending order
+func (s *Set8) GetAllElements() []uint8 {
+	return storageUnitsElements[uint8](s[:])
+}
+
+func (s *Set8) IsEq(otherSet *Set8) bool {
+	return *s == *otherSet
+}
+
+func (s *Set8) HasIntersection(otherSet *Set8) bool {
+	return storageUnitsHasIntersection(s[:], otherSet[:])
+}
+
+//IntersectionLen returns the number of elements in the intersection of both sets without creating the intersection set
+func (s *Set8) IntersectionLen(otherSet *Set8) int {
+	return storageUnitsIntersectionLen(s[:], otherSet[:])
+}
+
+//UnionLen returns the number of elements in the union of both sets without creating the union set
+func (s *Set8) UnionLen(otherSet *Set8) int {
+	return storageUnitsUnionLen(s[:], otherSet[:])
+}
+
+//DifferenceLen returns the number of elements that are in this set but not in otherSet, without creating the difference set
+func (s *Set8) DifferenceLen(otherSet *Set8) int {
+	return storageUnitsDifferenceLen(s[:], otherSet[:])
+}
+
+//JaccardSimilarity returns the size of the intersection divided by the size of the union of both sets.
+//Two empty sets are considered equal and so return 1.
+func (s *Set8) JaccardSimilarity(otherSet *Set8) float64 {
+	return storageUnitsJaccardSimilarity(s[:], otherSet[:])
+}
+
+//UnionWith adds all the elements of otherSet to this set. If otherSet is a Set8 this is the same as Union
+func (s *Set8) UnionWith(otherSet Set[uint8]) {
+
+	if o, ok := otherSet.(*Set8); ok {
+		s.Union(o)
+		return
+	}
+
+	storageUnitsUnionWith(s[:], otherSet)
+}
+
+//IntersectWith removes all the elements that are not in otherSet from this set
+func (s *Set8) IntersectWith(otherSet Set[uint8]) {
+
+	if o, ok := otherSet.(*Set8); ok {
+		storageUnitsIntersect(s[:], o[:])
+		return
+	}
+
+	storageUnitsIntersectWith(s[:], otherSet)
+}
+
+//ForEach calls f on all elements in ascending order, and stops early if f returns false
+func (s *Set8) ForEach(f func(x uint8) bool) {
+	storageUnitsForEach(s[:], f)
+}
+
+//ToMap returns a map that has all the elements of the set as keys
+func (s *Set8) ToMap() map[uint8]struct{} {
+	return storageUnitsToMap[uint8](s[:])
+}
+
+//Complement changes the set to have all values in [0, max] that were not in it, and removes all values bigger than max
+func (s *Set8) Complement(max uint8) {
+	storageUnitsComplement(s[:], max)
+}
+
+//GetComplement returns a new set with all values in [lo, hi] (inclusive of both) that are not in this set.
+//If lo > hi the returned set is empty.
+func (s *Set8) GetComplement(lo, hi uint8) *Set8 {
+
+	outSet := &Set8{}
+	storageUnitsGetComplement(s[:], lo, hi, outSet[:])
+	return outSet
+}
+
+//Intervals returns the elements of the set as a sorted list of [start, end] intervals (inclusive of both),
+//where each interval is the longest run of consecutive values in the set
+func (s *Set8) Intervals() [][2]uint8 {
+	return storageUnitsIntervals[uint8](s[:])
+}
+
+//IntervalsIter is like Intervals but calls f on each interval in ascending order instead of returning a list.
+//It stops early if f returns false.
+func (s *Set8) IntervalsIter(f func(start, end uint8) bool) {
+	storageUnitsIntervalsIter(s[:], f)
+}
+
+//Hash64 returns the same hash as NSet.Hash64 for a set with the same elements. Set8 is small enough that it isn't cached,
+//so unlike NSet.Hash64 it doesn't write to the set
+func (s *Set8) Hash64() uint64 {
+	return storageUnitsHash64(s[:])
+}
+
+//Fingerprint returns the same fingerprint as NSet.Fingerprint for a set with the same elements
+func (s *Set8) Fingerprint() [32]byte {
+	return storageUnitsFingerprint(s[:])
+}
+
+//Stats returns the memory usage and density of the set, as a single bucket
+func (s *Set8) Stats() Stats {
+	return storageUnitsStats(s[:])
+}
+
+//String returns a string of the storage as bytes separated by spaces. A comma is between each storage unit
+func (s *Set8) String() string {
+	return storageUnitsString(s[:])
+}
+
+func (s *Set8) Copy() *Set8 {
+	newSet := *s
+	return &newSet
+}
+
+//MarshalBinary encodes the set in the binary format of NSet.MarshalBinary
+func (s *Set8) MarshalBinary() ([]byte, error) {
+	return storageUnitsToNSet[uint8](s[:]).MarshalBinary()
+}
+
+//UnmarshalBinary replaces the contents of the set with the ones encoded in data by MarshalBinary of a Set8 or an NSet[uint8].
+//The set is only changed if data is valid.
+func (s *Set8) UnmarshalBinary(data []byte) error {
+	return s.decode(func(n *NSet[uint8]) error { return n.UnmarshalBinary(data) })
+}
+
+func (s *Set8) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+func (s *Set8) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+//WriteCompressed writes the set in the compressed format of NSet.WriteCompressed
+func (s *Set8) WriteCompressed(w io.Writer) error {
+	return storageUnitsToNSet[uint8](s[:]).WriteCompressed(w)
+}
+
+//ReadCompressed replaces the contents of the set with the ones written by WriteCompressed of a Set8 or an NSet[uint8].
+//The set is only changed if the whole set is read without errors.
+func (s *Set8) ReadCompressed(r io.Reader) error {
+	return s.decode(func(n *NSet[uint8]) error { return n.ReadCompressed(r) })
+}
+
+//EncodeString returns the compressed format of the set as URL-safe base64, like NSet.EncodeString
+func (s *Set8) EncodeString() string {
+	return storageUnitsToNSet[uint8](s[:]).EncodeString()
+}
+
+func (s *Set8) DecodeString(str string) error {
+	return s.decode(func(n *NSet[uint8]) error { return n.DecodeString(str) })
+}
+
+//EncodeHexString is like EncodeString but uses lowercase hex
+func (s *Set8) EncodeHexString() string {
+	return storageUnitsToNSet[uint8](s[:]).EncodeHexString()
+}
+
+func (s *Set8) DecodeHexString(str string) error {
+	return s.decode(func(n *NSet[uint8]) error { return n.DecodeHexString(str) })
+}
+
+//Value stores the set in a database like NSet.Value. A nil set is stored as NULL.
+func (s *Set8) Value() (driver.Value, error) {
+
+	if s == nil {
+		return nil, nil
+	}
+
+	return s.MarshalBinary()
+}
+
+//Scan replaces the contents of the set with a value read from a database, in any of the forms NSet.Scan accepts
+func (s *Set8) Scan(src any) error {
+	return s.decode(func(n *NSet[uint8]) error { return n.Scan(src) })
+}
+
+//decode reads the set into an NSet with decodeNSet, and only replaces the contents of the set if that succeeds
+func (s *Set8) decode(decodeNSet func(n *NSet[uint8]) error) error {
+
+	n := &NSet[uint8]{}
+	if err := decodeNSet(n); err != nil {
+		return err
+	}
+
+	storageUnitsFromNSet(s[:], n)
+	return nil
+}
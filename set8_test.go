@@ -0,0 +1,76 @@
+package nset_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/bloeys/nset"
+)
+
+func TestSet8(t *testing.T) {
+
+	IsEq(t, uintptr(32), unsafe.Sizeof(nset.Set8{}))
+
+	var s1 nset.Set8
+	s1.AddMany(0, 1, 63, 64, 200, math.MaxUint8)
+	s1.Remove(1)
+	s1.Remove(2)
+
+	AllTrue(t, s1.ContainsAll(0, 63, 64, 200, math.MaxUint8), !s1.ContainsAny(1, 2, 65, 254))
+	IsEq(t, 5, s1.Len())
+
+	elements := s1.GetAllElements()
+	AllTrue(t, len(elements) == 5, elements[0] == 0, elements[1] == 63, elements[2] == 64, elements[3] == 200, elements[4] == math.MaxUint8)
+
+	//Value semantics
+	s2 := s1
+	AllTrue(t, s1 == s2, s1.IsEq(&s2))
+
+	s2.Add(5)
+	AllTrue(t, s1 != s2, !s1.IsEq(&s2), !s1.Contains(5))
+
+	sCopy := s1.Copy()
+	sCopy.Add(6)
+	AllTrue(t, !s1.Contains(6))
+
+	//Set operations
+	var s3 nset.Set8
+	s3.AddMany(63, 100, math.MaxUint8)
+
+	AllTrue(t, s1.HasIntersection(&s3), s1.GetIntersection(&s3).ContainsAll(63, math.MaxUint8), !s1.GetIntersection(&s3).Contains(100))
+	IsEq(t, 2, s1.IntersectionLen(&s3))
+	IsEq(t, 6, s1.UnionLen(&s3))
+	IsEq(t, 3, s1.DifferenceLen(&s3))
+	IsEq(t, 2.0/6, s1.JaccardSimilarity(&s3))
+
+	s3.Union(&s1)
+	AllTrue(t, s3.ContainsAll(0, 63, 64, 100, 200, math.MaxUint8))
+	IsEq(t, 6, len(s3.ToMap()))
+
+	results := make([]bool, 3)
+	s3.ContainsMany([]uint8{0, 1, 100}, results)
+	AllTrue(t, results[0], !results[1], results[2])
+
+	//Matches NSet
+	n := nset.NewNSet[uint8]()
+	n.AddMany(0, 63, 64, 200, math.MaxUint8)
+	AllTrue(t, n.IsEq(nset.FromSlice(s1.GetAllElements())))
+
+	//Complement
+	comp := s1.GetComplement(60, 70)
+	AllTrue(t, comp.ContainsAll(60, 61, 62, 65, 70), !comp.ContainsAny(59, 63, 64, 71))
+	IsEq(t, 9, comp.Len())
+	IsEq(t, 0, s1.GetComplement(70, 60).Len())
+
+	n.Complement(100)
+	s1.Complement(100)
+	AllTrue(t, n.IsEq(nset.FromSlice(s1.GetAllElements())), s1.Contains(100), !s1.ContainsAny(0, 63, 101, math.MaxUint8))
+}
+
+func TestSet8MatchesNSet(t *testing.T) {
+
+	rand.Seed(RandSeed)
+	testFlatSetAgainstNSet(t, func() flatSet[uint8] { return &nset.Set8{} }, math.MaxUint8)
+}
@@ -0,0 +1,177 @@
+package nset_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestSetImplementations(t *testing.T) {
+
+	rand.Seed(RandSeed)
+
+	testSetAgainstMapSet(t, func() nset.Set[uint8] { return nset.NewNSet[uint8]() }, math.MaxUint8)
+	testSetAgainstMapSet(t, func() nset.Set[uint8] { return &nset.Set8{} }, math.MaxUint8)
+	testSetAgainstMapSet(t, func() nset.Set[uint16] { return nset.NewNSet[uint16]() }, math.MaxUint16)
+	testSetAgainstMapSet(t, func() nset.Set[uint16] { return &nset.Set16{} }, math.MaxUint16)
+	testSetAgainstMapSet(t, func() nset.Set[uint32] { return nset.NewNSet[uint32]() }, 10_000_000)
+	testSetAgainstMapSet(t, func() nset.Set[uint32] { return nset.NewNSet[uint32](nset.WithBucketIndexingBits(2)) }, 10_000_000)
+
+	//Mixing implementations
+	s1 := nset.NewNSet[uint8]()
+	s1.AddMany(1, 2, 3, 200)
+
+	s2 := &nset.Set8{}
+	s2.AddMany(2, 3, 4)
+
+	s1.IntersectWith(s2)
+	AllTrue(t, s1.ContainsAll(2, 3), !s1.ContainsAny(1, 4, 200))
+
+	s2.UnionWith(nset.MapSet[uint8]{7: {}, 255: {}})
+	AllTrue(t, s2.ContainsAll(2, 3, 4, 7, 255))
+
+	//MapSet supports types NSet doesn't
+	bigValues := nset.NewMapSet[uint64]()
+	bigValues.Add(math.MaxUint64)
+	AllTrue(t, bigValues.Contains(math.MaxUint64), bigValues.Len() == 1)
+}
+
+func testSetAgainstMapSet[T nset.IntsIf](t *testing.T, newSet func() nset.Set[T], maxValue uint32) {
+
+	randValues := func(count int) []T {
+
+		values := make([]T, count)
+		for i := 0; i < len(values); i++ {
+			values[i] = T(rand.Uint32() % (maxValue + 1))
+		}
+
+		return values
+	}
+
+	s1, m1 := newSet(), nset.NewMapSet[T]()
+	s2, m2 := newSet(), nset.NewMapSet[T]()
+	for _, x := range randValues(500) {
+		s1.Add(x)
+		m1.Add(x)
+	}
+
+	for _, x := range randValues(500) {
+		s2.Add(x)
+		m2.Add(x)
+	}
+
+	//Only remove values that exist
+	for _, x := range randValues(200) {
+		if m1.Contains(x) {
+			s1.Remove(x)
+			m1.Remove(x)
+		}
+	}
+
+	assertSameAsMapSet(t, s1, m1)
+	assertSameAsMapSet(t, s2, m2)
+
+	union, mUnion := newSet(), nset.NewMapSet[T]()
+	union.UnionWith(s1)
+	union.UnionWith(s2)
+	mUnion.UnionWith(m1)
+	mUnion.UnionWith(m2)
+	assertSameAsMapSet(t, union, mUnion)
+
+	s1.IntersectWith(s2)
+	m1.IntersectWith(m2)
+	assertSameAsMapSet(t, s1, m1)
+
+	//Intersecting with a different implementation
+	s2.IntersectWith(m1)
+	m2.IntersectWith(m1)
+	assertSameAsMapSet(t, s2, m2)
+}
+
+func assertSameAsMapSet[T nset.IntsIf](t *testing.T, s nset.Set[T], m nset.MapSet[T]) {
+
+	IsEq(t, m.Len(), s.Len())
+
+	lastValue := -1
+	s.ForEach(func(x T) bool {
+
+		AllTrue(t, m.Contains(x), int(x) > lastValue)
+		lastValue = int(x)
+		return true
+	})
+
+	m.ForEach(func(x T) bool {
+		AllTrue(t, s.Contains(x))
+		return true
+	})
+}
+
+//flatSet has the methods Set8 and Set16 share with NSet that testFlatSetAgainstNSet checks
+type flatSet[T nset.IntsIf] interface {
+	nset.Set[T]
+	Rank(x T) int
+	Intervals() [][2]T
+	Hash64() uint64
+	Fingerprint() [32]byte
+	Stats() nset.Stats
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+	EncodeString() string
+	DecodeString(s string) error
+}
+
+//testFlatSetAgainstNSet fills a Set8 or Set16 and an NSet with the same random values, and checks
+//that their queries match and that encodings written by one can be read by the other
+func testFlatSetAgainstNSet[T nset.IntsIf](t *testing.T, newSet func() flatSet[T], maxValue uint32) {
+
+	s := newSet()
+	n := nset.NewNSet[T]()
+	for i := 0; i < 300; i++ {
+
+		//Runs of values so there are intervals longer than one
+		x := T(rand.Uint32() % (maxValue + 1))
+		for j := 0; j < rand.Intn(70) && uint32(x) < maxValue; j++ {
+			s.Add(x)
+			n.Add(x)
+			x++
+		}
+	}
+
+	IsEq(t, n.Len(), s.Len())
+	IsEq(t, n.Hash64(), s.Hash64())
+	IsEq(t, n.Fingerprint(), s.Fingerprint())
+	nIntervals, sIntervals := n.Intervals(), s.Intervals()
+	if IsEq(t, len(nIntervals), len(sIntervals)) {
+		for i := 0; i < len(nIntervals); i++ {
+			IsEq(t, nIntervals[i], sIntervals[i])
+		}
+	}
+	IsEq(t, uint64(n.Len()), s.Stats().ElementCount)
+	for i := 0; i < 100; i++ {
+		x := T(rand.Uint32() % (maxValue + 1))
+		IsEq(t, n.Rank(x), s.Rank(x))
+	}
+
+	data, err := s.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	n2 := &nset.NSet[T]{}
+	AllTrue(t, n2.UnmarshalBinary(data) == nil, n2.IsEq(n))
+
+	s2 := newSet()
+	data, _ = n.MarshalBinary()
+	AllTrue(t, s2.UnmarshalBinary(data) == nil, s2.Hash64() == n.Hash64(), s2.Len() == n.Len())
+
+	s3 := newSet()
+	AllTrue(t, s3.DecodeString(n.EncodeString()) == nil, s3.Fingerprint() == n.Fingerprint())
+	IsEq(t, n.EncodeString(), s3.EncodeString())
+
+	//A failed decode leaves the set unchanged
+	AllTrue(t, s3.UnmarshalBinary([]byte("bad")) != nil, s3.Len() == n.Len())
+
+	s4 := newSet()
+	IsEq(t, nset.NewNSet[T]().Hash64(), s4.Hash64())
+	IsEq(t, 0, len(s4.Intervals()))
+}
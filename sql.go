@@ -0,0 +1,85 @@
+package nset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ sql.Scanner   = &NSet[uint8]{}
+	_ driver.Valuer = &NSet[uint8]{}
+)
+
+//Value stores the set in a database as its binary format (see MarshalBinary), for example in a Postgres bytea or SQLite blob column.
+//A nil set is stored as NULL.
+func (n *NSet[T]) Value() (driver.Value, error) {
+
+	if n == nil {
+		return nil, nil
+	}
+
+	return n.MarshalBinary()
+}
+
+//Scan replaces the contents of the set with a value read from a database. The value can be:
+//
+//   - The binary format written by Value (see MarshalBinary), which also sets the bucket layout
+//   - A Postgres integer array in its text form, like '{1,2,3}', for example from an int[] column
+//   - NULL, which makes the set empty
+func (n *NSet[T]) Scan(src any) error {
+
+	switch src := src.(type) {
+	case nil:
+		*n = NSet[T]{}
+		return nil
+
+	case []byte:
+		return n.scanBytes(src)
+
+	case string:
+		return n.scanBytes([]byte(src))
+
+	default:
+		return fmt.Errorf("nset: can't scan %T into NSet", src)
+	}
+}
+
+func (n *NSet[T]) scanBytes(src []byte) error {
+
+	if len(src) > 0 && src[0] == '{' {
+		return n.scanPostgresArray(string(src))
+	}
+
+	//UnmarshalBinary copies the data, so it's fine that src is only valid until Scan returns
+	return n.UnmarshalBinary(src)
+}
+
+//scanPostgresArray replaces the contents of the set with the elements of a one dimensional Postgres array like '{1,2,3}'
+func (n *NSet[T]) scanPostgresArray(src string) error {
+
+	if len(src) < 2 || src[len(src)-1] != '}' {
+		return fmt.Errorf("nset: invalid Postgres array %q", src)
+	}
+
+	newSet := NewNSet[T]()
+
+	elements := strings.TrimSpace(src[1 : len(src)-1])
+	if elements != "" {
+
+		for _, element := range strings.Split(elements, ",") {
+
+			x, err := strconv.ParseUint(strings.TrimSpace(element), 10, int(typeBitsOf[T]()))
+			if err != nil {
+				return fmt.Errorf("nset: invalid element in Postgres array %q: %w", src, err)
+			}
+
+			newSet.Add(T(x))
+		}
+	}
+
+	*n = *newSet
+	return nil
+}
@@ -0,0 +1,143 @@
+package nset_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+//fakeDriver is a database/sql driver with a single column table, where 'INSERT' adds a row with the first argument
+//and 'SELECT' returns all rows
+type fakeDriver struct {
+	rows []driver.Value
+}
+
+//fakeSQLDriver is registered once, as registering a name twice panics (e.g. with go test -count=2)
+var fakeSQLDriver = &fakeDriver{}
+
+func init() {
+	sql.Register("nset-fake", fakeSQLDriver)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	if s.query == "INSERT" {
+		return 1
+	}
+	return 0
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.rows = append(s.d.rows, args[0])
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"ids"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	dest[0] = r.rows[0]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestNSetSQL(t *testing.T) {
+
+	d := fakeSQLDriver
+	d.rows = nil
+
+	db, err := sql.Open("nset-fake", "")
+	AllTrue(t, err == nil)
+	defer db.Close()
+
+	n1 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(3))
+	n1.AddMany(1, 2, 3, 1000, math.MaxUint32)
+
+	var nilSet *nset.NSet[uint32]
+	for _, v := range []any{n1, nilSet, "{5, 6,7}", []byte("{}")} {
+		_, err = db.Exec("INSERT", v)
+		AllTrue(t, err == nil)
+	}
+
+	//Values are stored as the binary format
+	data, err := n1.MarshalBinary()
+	AllTrue(t, err == nil)
+	IsEq(t, string(data), string(d.rows[0].([]byte)))
+	AllTrue(t, d.rows[1] == nil)
+
+	rows, err := db.Query("SELECT")
+	AllTrue(t, err == nil)
+	defer rows.Close()
+
+	expected := []*nset.NSet[uint32]{n1, nset.NewNSet[uint32](), nset.FromSlice([]uint32{5, 6, 7}), nset.NewNSet[uint32]()}
+	for i := 0; rows.Next(); i++ {
+
+		//The set starts with elements to check they are replaced
+		n := nset.FromSlice([]uint32{100})
+		AllTrue(t, rows.Scan(n) == nil)
+		AllTrue(t, n.IsEq(expected[i]))
+	}
+	AllTrue(t, rows.Err() == nil)
+
+	//Layout comes from the binary format
+	var n2 nset.NSet[uint32]
+	AllTrue(t, db.QueryRow("SELECT").Scan(&n2) == nil)
+	IsEq(t, uint8(3), n2.BucketIndexingBits())
+
+	//Invalid values
+	var n3 nset.NSet[uint8]
+	AllTrue(t, n3.Scan("{1,256}") != nil, n3.Scan("{1,-1}") != nil, n3.Scan("{1,NULL}") != nil, n3.Scan("{1") != nil)
+	AllTrue(t, n3.Scan(5) != nil, errors.Is(n3.Scan([]byte("NSET")), nset.ErrInvalidBinaryData))
+	AllTrue(t, n3.Scan(" {1}") != nil, n3.Len() == 0)
+}
@@ -0,0 +1,96 @@
+package nset
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+//DensityHistogramSize is the number of ranges bucket densities are grouped into in Stats.DensityHistogram
+const DensityHistogramSize = 10
+
+//Stats describes the memory usage and density of an NSet. Set8 and Set16 are described as a single bucket
+type Stats struct {
+	//AllocatedBytes is the total memory held by the set, including bucket capacity that is allocated but not yet used
+	AllocatedBytes uint64
+	//UsedStorageUnits is the number of storage units in use by all buckets, which is the same as NSet.StorageUnitCount
+	UsedStorageUnits uint64
+	//CapacityStorageUnits is the number of storage units allocated by all buckets
+	CapacityStorageUnits uint64
+	//EmptyStorageUnits is the number of used storage units that have no elements in them.
+	//A high count relative to UsedStorageUnits means the set holds a lot of memory for nothing (e.g. after many removes)
+	EmptyStorageUnits uint64
+	ElementCount      uint64
+
+	Buckets []BucketStats
+
+	//DensityHistogram counts non-empty buckets by their density, where DensityHistogram[i] is the number of buckets
+	//with a density in [i/DensityHistogramSize, (i+1)/DensityHistogramSize). A density of 1 is counted in the last entry.
+	DensityHistogram [DensityHistogramSize]uint32
+}
+
+type BucketStats struct {
+	UsedStorageUnits     uint32
+	CapacityStorageUnits uint32
+	EmptyStorageUnits    uint32
+	ElementCount         uint32
+	//Density is the number of elements divided by the number of values the used storage units can hold.
+	//A bucket with no storage units has a density of 0.
+	Density float64
+}
+
+//Stats returns memory usage and density information of the set.
+//This goes over all storage units so it should not be called in hot paths.
+func (n *NSet[T]) Stats() Stats {
+
+	n.lazyInit()
+	s := Stats{
+		AllocatedBytes: uint64(unsafe.Sizeof(*n)),
+		Buckets:        make([]BucketStats, len(n.Buckets)),
+	}
+
+	//Small types (e.g. uint8) have buckets that are smaller than a single storage unit
+	valuesPerStorageUnit := uint64(StorageTypeBits)
+	if uint64(1)<<n.shiftAmount() < valuesPerStorageUnit {
+		valuesPerStorageUnit = uint64(1) << n.shiftAmount()
+	}
+
+	for i := 0; i < len(n.Buckets); i++ {
+		s.addBucket(&s.Buckets[i], n.Buckets[i].Data, valuesPerStorageUnit)
+	}
+
+	s.AllocatedBytes += uint64(cap(n.Buckets))*uint64(unsafe.Sizeof(Bucket{})) + uint64(cap(n.bucketHashes))*uint64(unsafe.Sizeof(uint64(0)))
+	s.AllocatedBytes += s.CapacityStorageUnits * uint64(unsafe.Sizeof(StorageType(0)))
+	return s
+}
+
+//addBucket fills bs with the stats of a bucket with the given storage units, and adds them to the totals of s
+func (s *Stats) addBucket(bs *BucketStats, data []StorageType, valuesPerStorageUnit uint64) {
+
+	bs.UsedStorageUnits = uint32(len(data))
+	bs.CapacityStorageUnits = uint32(cap(data))
+	for j := 0; j < len(data); j++ {
+
+		onesCount := bits.OnesCount64(uint64(data[j]))
+		if onesCount == 0 {
+			bs.EmptyStorageUnits++
+		}
+
+		bs.ElementCount += uint32(onesCount)
+	}
+
+	if bs.UsedStorageUnits > 0 {
+
+		bs.Density = float64(bs.ElementCount) / float64(uint64(bs.UsedStorageUnits)*valuesPerStorageUnit)
+
+		histIndex := int(bs.Density * DensityHistogramSize)
+		if histIndex >= DensityHistogramSize {
+			histIndex = DensityHistogramSize - 1
+		}
+		s.DensityHistogram[histIndex]++
+	}
+
+	s.UsedStorageUnits += uint64(bs.UsedStorageUnits)
+	s.CapacityStorageUnits += uint64(bs.CapacityStorageUnits)
+	s.EmptyStorageUnits += uint64(bs.EmptyStorageUnits)
+	s.ElementCount += uint64(bs.ElementCount)
+}
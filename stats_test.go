@@ -0,0 +1,52 @@
+package nset_test
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/bloeys/nset"
+)
+
+func TestNSetStats(t *testing.T) {
+
+	n := nset.NewNSet[uint32]()
+	n.AddMany(0, 1, 2, 63, 1000, math.MaxUint32)
+	n.Remove(1000)
+
+	s := n.Stats()
+	IsEq(t, 5, n.Len())
+	IsEq(t, uint64(5), s.ElementCount)
+	IsEq(t, uint64(n.StorageUnitCount), s.UsedStorageUnits)
+	IsEq(t, nset.BucketCount, len(s.Buckets))
+	AllTrue(t, s.CapacityStorageUnits >= s.UsedStorageUnits, s.AllocatedBytes >= s.CapacityStorageUnits*8)
+
+	//Bucket 0 has 0,1,2,63 in the first unit and an empty unit where 1000 was
+	b0 := s.Buckets[0]
+	IsEq(t, uint32(16), b0.UsedStorageUnits)
+	IsEq(t, uint32(15), b0.EmptyStorageUnits)
+	IsEq(t, uint32(4), b0.ElementCount)
+	IsEq(t, 4.0/(16*64), b0.Density)
+	IsEq(t, uint64(15+s.Buckets[nset.BucketCount-1].EmptyStorageUnits), s.EmptyStorageUnits)
+
+	histTotal := uint32(0)
+	for i := 0; i < len(s.DensityHistogram); i++ {
+		histTotal += s.DensityHistogram[i]
+	}
+	IsEq(t, uint32(2), histTotal)
+	IsEq(t, uint32(2), s.DensityHistogram[0])
+
+	//Full sets are in the last histogram entry
+	full := nset.FromRange[uint8](0, math.MaxUint8)
+	fullStats := full.Stats()
+	IsEq(t, uint32(nset.BucketCount), fullStats.DensityHistogram[nset.DensityHistogramSize-1])
+	IsEq(t, 1.0, fullStats.Buckets[0].Density)
+
+	IsEq(t, 0, nset.NewNSet[uint16]().Len())
+
+	//An empty set only holds the NSet struct and its buckets
+	setSize := uint64(unsafe.Sizeof(nset.NSet[uint32]{}))
+	bucketSize := uint64(unsafe.Sizeof(nset.Bucket{}))
+	IsEq(t, setSize+nset.BucketCount*bucketSize, nset.NewNSet[uint32]().Stats().AllocatedBytes)
+	IsEq(t, setSize+4*bucketSize, nset.NewNSet[uint32](nset.WithBucketIndexingBits(2)).Stats().AllocatedBytes)
+}
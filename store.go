@@ -0,0 +1,361 @@
+package nset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//Names of the files of a Store inside its directory
+const (
+	storeCheckpointFileName     = "checkpoint"
+	storeCheckpointTempFileName = "checkpoint.tmp"
+	storeWALFileName            = "wal"
+)
+
+//The WAL is a list of fixed size records (all integers are little endian):
+//
+//	op (1 byte) | value (uint32) | CRC-32 (IEEE) of the op and value (uint32)
+//
+//A record that is cut short or has a wrong CRC marks the end of the log, as it is what a crash during a write leaves behind.
+const (
+	storeOpAdd    = 1
+	storeOpRemove = 2
+
+	storeRecordSize = 9
+)
+
+//Store is an NSet that is kept on disk and survives crashes. Changes are appended to a write-ahead log (WAL) and synced
+//before they are applied, and the whole set is written to a checkpoint file every so often, after which the log is cleared.
+//
+//Opening a store loads the checkpoint and replays the log on top of it. Adds and removes are idempotent, so
+//replaying records that are already in the checkpoint (e.g. after a crash right after a checkpoint) is harmless.
+//
+//A Store is not safe for concurrent use, and a directory must only be used by one Store at a time.
+type Store[T IntsIf] struct {
+	set *NSet[T]
+	dir string
+	wal storeWAL
+	//walRecordCount is the number of records in the WAL since the last checkpoint, so the WAL ends at walRecordCount*storeRecordSize
+	walRecordCount  int
+	checkpointEvery int
+	//err is set when a failed write couldn't be undone. The WAL might then end with garbage, so all later changes fail with err
+	err error
+	//checkpointErr is the result of the last checkpoint, see LastCheckpointErr
+	checkpointErr error
+}
+
+//storeWAL is the part of *os.File used for the WAL
+type storeWAL interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+//OpenStore opens the store in dir, creating dir and an empty store if needed. A checkpoint is written automatically
+//every checkpointEvery records, and never if checkpointEvery <= 0.
+//The options are only used when creating a new store, otherwise the layout of the stored set is kept.
+func OpenStore[T IntsIf](dir string, checkpointEvery int, options ...Option) (*Store[T], error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Store[T]{
+		dir:             dir,
+		checkpointEvery: checkpointEvery,
+	}
+
+	checkpoint, err := os.ReadFile(filepath.Join(dir, storeCheckpointFileName))
+	switch {
+	case err == nil:
+		s.set = &NSet[T]{}
+		if err := s.set.UnmarshalBinary(checkpoint); err != nil {
+			return nil, fmt.Errorf("%s: loading checkpoint: %w", dir, err)
+		}
+
+	case errors.Is(err, os.ErrNotExist):
+
+		//The initial checkpoint keeps the layout of the new set
+		s.set = NewNSet[T](options...)
+		if err := s.writeCheckpoint(); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, storeWALFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.wal = wal
+
+	if err := s.replayWAL(); err != nil {
+		s.wal.Close()
+		return nil, fmt.Errorf("%s: replaying WAL: %w", dir, err)
+	}
+
+	return s, nil
+}
+
+//replayWAL applies all the valid records of the WAL to the set, and cuts off whatever comes after them
+//so that new records are appended right after the last valid one
+func (s *Store[T]) replayWAL() error {
+
+	data, err := io.ReadAll(s.wal)
+	if err != nil {
+		return err
+	}
+
+	validSize := 0
+	for ; validSize+storeRecordSize <= len(data); validSize += storeRecordSize {
+
+		record := data[validSize : validSize+storeRecordSize]
+		if crc32.ChecksumIEEE(record[:5]) != binary.LittleEndian.Uint32(record[5:]) {
+			break
+		}
+
+		x := T(binary.LittleEndian.Uint32(record[1:]))
+		if op := record[0]; op == storeOpAdd {
+			s.set.Add(x)
+		} else if op == storeOpRemove {
+			s.set.Remove(x)
+		} else {
+			break
+		}
+
+		s.walRecordCount++
+	}
+
+	if validSize < len(data) {
+
+		if err := s.wal.Truncate(int64(validSize)); err != nil {
+			return err
+		}
+
+		if err := s.wal.Sync(); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.wal.Seek(int64(validSize), io.SeekStart)
+	return err
+}
+
+//appendRecords writes and syncs one WAL record per value, then applies them to the set.
+//If writing or syncing fails the WAL is cut back to where it was, so the records of a failed call are never replayed
+//and don't hide the records written after them.
+func (s *Store[T]) appendRecords(op byte, values []T) error {
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	data := make([]byte, len(values)*storeRecordSize)
+	for i, x := range values {
+
+		record := data[i*storeRecordSize : (i+1)*storeRecordSize]
+		record[0] = op
+		binary.LittleEndian.PutUint32(record[1:], uint32(x))
+		binary.LittleEndian.PutUint32(record[5:], crc32.ChecksumIEEE(record[:5]))
+	}
+
+	_, err := s.wal.Write(data)
+	if err == nil {
+		err = s.wal.Sync()
+	}
+
+	if err != nil {
+		return s.rollbackWAL(err)
+	}
+
+	for _, x := range values {
+		if op == storeOpAdd {
+			s.set.Add(x)
+		} else {
+			s.set.Remove(x)
+		}
+	}
+
+	//The change is durable once the WAL is synced, so a failed automatic checkpoint doesn't fail it.
+	//The checkpoint is tried again on the next change, and its error is kept for LastCheckpointErr
+	s.walRecordCount += len(values)
+	if s.checkpointEvery > 0 && s.walRecordCount >= s.checkpointEvery {
+		s.Checkpoint()
+	}
+
+	return nil
+}
+
+//rollbackWAL cuts the WAL back to its last record after a failed write, and returns writeErr.
+//If that fails too, the store can't know what the WAL ends with, and all later changes fail
+func (s *Store[T]) rollbackWAL(writeErr error) error {
+
+	size := int64(s.walRecordCount * storeRecordSize)
+	err := s.wal.Truncate(size)
+	if err == nil {
+		_, err = s.wal.Seek(size, io.SeekStart)
+	}
+
+	if err == nil {
+		err = s.wal.Sync()
+	}
+
+	if err != nil {
+		s.err = fmt.Errorf("%s: undoing failed WAL write (%v): %w", s.dir, writeErr, err)
+		return s.err
+	}
+
+	return writeErr
+}
+
+//Add adds x to the set. The change is on disk when Add returns without an error
+func (s *Store[T]) Add(x T) error {
+	return s.appendRecords(storeOpAdd, []T{x})
+}
+
+//AddMany adds all values to the set. This is faster than calling Add for each value as the WAL is synced only once
+func (s *Store[T]) AddMany(values ...T) error {
+	return s.appendRecords(storeOpAdd, values)
+}
+
+//Remove removes x from the set. The change is on disk when Remove returns without an error
+func (s *Store[T]) Remove(x T) error {
+	return s.appendRecords(storeOpRemove, []T{x})
+}
+
+//RemoveMany removes all values from the set. This is faster than calling Remove for each value as the WAL is synced only once
+func (s *Store[T]) RemoveMany(values ...T) error {
+	return s.appendRecords(storeOpRemove, values)
+}
+
+func (s *Store[T]) Contains(x T) bool {
+	return s.set.Contains(x)
+}
+
+func (s *Store[T]) Len() int {
+	return s.set.Len()
+}
+
+//ForEach calls f on each element of the set in ascending order, and stops early if f returns false
+func (s *Store[T]) ForEach(f func(x T) bool) {
+	s.set.ForEach(f)
+}
+
+//Copy returns a copy of the set that isn't connected to the store
+func (s *Store[T]) Copy() *NSet[T] {
+	return s.set.Copy()
+}
+
+//LastCheckpointErr returns the error of the last checkpoint, automatic or not, or nil if it succeeded.
+//Changes that trigger an automatic checkpoint don't return its error, as they are already on disk in the WAL.
+func (s *Store[T]) LastCheckpointErr() error {
+	return s.checkpointErr
+}
+
+//Checkpoint writes the whole set to the checkpoint file and clears the WAL.
+//The new checkpoint replaces the old one atomically, so a crash at any point leaves a usable store.
+func (s *Store[T]) Checkpoint() error {
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.checkpointErr = s.checkpoint()
+	return s.checkpointErr
+}
+
+func (s *Store[T]) checkpoint() error {
+
+	if err := s.writeCheckpoint(); err != nil {
+		return err
+	}
+
+	//The checkpoint is safely on disk, so the WAL is no longer needed
+	if err := s.wal.Truncate(0); err != nil {
+		return err
+	}
+	s.walRecordCount = 0
+
+	//New records would be written past the end of the WAL, leaving a gap that ends the log on replay
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		s.err = fmt.Errorf("%s: seeking to the start of the WAL: %w", s.dir, err)
+		return s.err
+	}
+
+	return s.wal.Sync()
+}
+
+//writeCheckpoint replaces the checkpoint file with the current set
+func (s *Store[T]) writeCheckpoint() error {
+
+	data, err := s.set.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tempPath := filepath.Join(s.dir, storeCheckpointTempFileName)
+	if err := writeFileSync(tempPath, data); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tempPath, filepath.Join(s.dir, storeCheckpointFileName)); err != nil {
+		return err
+	}
+
+	return syncDir(s.dir)
+}
+
+//Close closes the WAL. No checkpoint is written, so the next OpenStore replays the WAL
+func (s *Store[T]) Close() error {
+	return s.wal.Close()
+}
+
+//writeFileSync writes data to a new file at path and syncs it
+func writeFileSync(path string, data []byte) error {
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+//syncDir syncs the directory entries of dir, so renames inside it are on disk
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	//Some systems (e.g. Windows) can't sync directories, in which case there is nothing more we can do
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, os.ErrPermission) {
+		d.Close()
+		return err
+	}
+
+	return d.Close()
+}
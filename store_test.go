@@ -0,0 +1,246 @@
+package nset_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestStore(t *testing.T) {
+
+	dir := t.TempDir()
+
+	s, err := nset.OpenStore[uint32](dir, 0, nset.WithBucketIndexingBits(4))
+	AllTrue(t, err == nil)
+	AllTrue(t, s.AddMany(1, 2, 3, 1000, math.MaxUint32) == nil, s.Remove(2) == nil, s.Add(5) == nil)
+	AllTrue(t, s.Close() == nil)
+
+	expected := nset.FromSlice([]uint32{1, 3, 5, 1000, math.MaxUint32})
+
+	//Only the WAL
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil)
+	AllTrue(t, s.Copy().IsEq(expected))
+
+	//Checkpoint and then more changes in the WAL
+	AllTrue(t, s.Checkpoint() == nil)
+	AllTrue(t, s.RemoveMany(1, 3) == nil, s.Add(7) == nil)
+	AllTrue(t, s.Close() == nil)
+
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil)
+	AllTrue(t, s.Copy().IsEq(nset.FromSlice([]uint32{5, 7, 1000, math.MaxUint32})), !s.Contains(1), s.Len() == 4)
+
+	//The layout comes from the checkpoint
+	IsEq(t, uint8(4), s.Copy().BucketIndexingBits())
+	AllTrue(t, s.Close() == nil)
+
+	//Automatic checkpoints clear the WAL
+	s, err = nset.OpenStore[uint32](dir, 3)
+	AllTrue(t, err == nil)
+	AllTrue(t, s.AddMany(10, 11, 12) == nil)
+
+	info, err := os.Stat(filepath.Join(dir, "wal"))
+	AllTrue(t, err == nil, info.Size() == 0)
+	AllTrue(t, s.Close() == nil)
+
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil, s.Len() == 7, s.Contains(12))
+	AllTrue(t, s.Close() == nil)
+}
+
+func TestStoreRecovery(t *testing.T) {
+
+	type op struct {
+		remove bool
+		x      uint16
+	}
+
+	ops := []op{{false, 1}, {false, 2}, {false, 300}, {true, 2}, {false, math.MaxUint16}, {true, 1}, {false, 2}}
+
+	//expectedAfter returns the set after applying the first count ops to base
+	expectedAfter := func(base *nset.NSet[uint16], count int) *nset.NSet[uint16] {
+
+		n := base.Copy()
+		for _, o := range ops[:count] {
+			if o.remove {
+				n.Remove(o.x)
+			} else {
+				n.Add(o.x)
+			}
+		}
+
+		return n
+	}
+
+	for _, withCheckpoint := range []bool{false, true} {
+
+		dir := t.TempDir()
+		s, err := nset.OpenStore[uint16](dir, 0)
+		AllTrue(t, err == nil)
+
+		base := nset.NewNSet[uint16]()
+		if withCheckpoint {
+			base.AddMany(1, 50, 60)
+			AllTrue(t, s.AddMany(1, 50, 60) == nil, s.Checkpoint() == nil)
+		}
+
+		for _, o := range ops {
+			if o.remove {
+				AllTrue(t, s.Remove(o.x) == nil)
+			} else {
+				AllTrue(t, s.Add(o.x) == nil)
+			}
+		}
+		AllTrue(t, s.Close() == nil)
+
+		walPath := filepath.Join(dir, "wal")
+		wal, err := os.ReadFile(walPath)
+		AllTrue(t, err == nil)
+
+		recordSize := len(wal) / len(ops)
+		IsEq(t, len(ops)*recordSize, len(wal))
+
+		//Cutting the log anywhere (like a crash during a write) recovers all the complete records before the cut
+		for cut := 0; cut <= len(wal); cut++ {
+
+			AllTrue(t, os.WriteFile(walPath, wal[:cut], 0o644) == nil)
+
+			s, err = nset.OpenStore[uint16](dir, 0)
+			AllTrue(t, err == nil)
+			if !s.Copy().IsEq(expectedAfter(base, cut/recordSize)) {
+				t.Fatalf("withCheckpoint=%v cut=%d: got %v", withCheckpoint, cut, s.Copy())
+			}
+
+			//New records go right after the recovered ones
+			AllTrue(t, s.Add(1234) == nil, s.Close() == nil)
+
+			s, err = nset.OpenStore[uint16](dir, 0)
+			AllTrue(t, err == nil)
+			expected := expectedAfter(base, cut/recordSize)
+			expected.Add(1234)
+			if !s.Copy().IsEq(expected) {
+				t.Fatalf("withCheckpoint=%v cut=%d: got %v after adding", withCheckpoint, cut, s.Copy())
+			}
+			AllTrue(t, s.Close() == nil)
+		}
+
+		//A corrupted record ends the log
+		corrupted := append([]byte{}, wal...)
+		corrupted[3*recordSize+2] ^= 0xFF
+		AllTrue(t, os.WriteFile(walPath, corrupted, 0o644) == nil)
+
+		s, err = nset.OpenStore[uint16](dir, 0)
+		AllTrue(t, err == nil, s.Copy().IsEq(expectedAfter(base, 3)))
+		AllTrue(t, s.Close() == nil)
+	}
+}
+
+//faultyWAL writes only half of what it is given if shortWrite is set, and fails to truncate if failTruncate is set
+type faultyWAL struct {
+	nset.StoreWAL
+	shortWrite   bool
+	failTruncate bool
+}
+
+func (w *faultyWAL) Write(data []byte) (int, error) {
+
+	if !w.shortWrite {
+		return w.StoreWAL.Write(data)
+	}
+
+	n, err := w.StoreWAL.Write(data[:len(data)/2])
+	if err != nil {
+		return n, err
+	}
+
+	return n, io.ErrShortWrite
+}
+
+func (w *faultyWAL) Truncate(size int64) error {
+
+	if w.failTruncate {
+		return errors.New("truncate failed")
+	}
+
+	return w.StoreWAL.Truncate(size)
+}
+
+func TestStoreFailedWrite(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil)
+	AllTrue(t, s.AddMany(1, 2) == nil)
+
+	wal := &faultyWAL{shortWrite: true}
+	nset.WrapStoreWAL(s, func(w nset.StoreWAL) nset.StoreWAL {
+		wal.StoreWAL = w
+		return wal
+	})
+
+	//A short write is undone, so the set and the WAL are as before and later records aren't lost
+	AllTrue(t, errors.Is(s.AddMany(3, 4, 5), io.ErrShortWrite), !s.Contains(3), s.Len() == 2)
+
+	info, err := os.Stat(filepath.Join(dir, "wal"))
+	AllTrue(t, err == nil)
+	IsEq(t, int64(2*9), info.Size())
+
+	wal.shortWrite = false
+	AllTrue(t, s.Add(6) == nil, s.Close() == nil)
+
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil, s.Copy().IsEq(nset.FromSlice([]uint32{1, 2, 6})))
+
+	//If the failed write can't be undone, the store refuses all later changes
+	wal = &faultyWAL{shortWrite: true, failTruncate: true}
+	nset.WrapStoreWAL(s, func(w nset.StoreWAL) nset.StoreWAL {
+		wal.StoreWAL = w
+		return wal
+	})
+
+	err = s.Add(7)
+	AllTrue(t, err != nil, !errors.Is(err, io.ErrShortWrite), !s.Contains(7))
+
+	wal.shortWrite, wal.failTruncate = false, false
+	AllTrue(t, s.Add(8) == err, s.Checkpoint() == err, !s.Contains(8))
+	AllTrue(t, s.Close() == nil)
+
+	//The garbage at the end of the WAL is dropped when opening the store again
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil, s.Copy().IsEq(nset.FromSlice([]uint32{1, 2, 6})))
+	AllTrue(t, s.Add(9) == nil, s.Close() == nil)
+}
+
+func TestStoreFailedCheckpoint(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := nset.OpenStore[uint32](dir, 2)
+	AllTrue(t, err == nil)
+
+	//A directory where the temporary checkpoint file goes makes writing checkpoints fail
+	tempPath := filepath.Join(dir, "checkpoint.tmp")
+	AllTrue(t, os.Mkdir(tempPath, 0o755) == nil)
+
+	//The changes are in the WAL, so they succeed even though the automatic checkpoint fails
+	AllTrue(t, s.AddMany(1, 2) == nil, s.Contains(1), s.LastCheckpointErr() != nil)
+	AllTrue(t, s.Add(3) == nil, s.Contains(3), s.LastCheckpointErr() != nil)
+	AllTrue(t, s.Checkpoint() != nil)
+
+	//Once checkpoints work again the error is cleared and the WAL is emptied
+	AllTrue(t, os.Remove(tempPath) == nil)
+	AllTrue(t, s.Add(4) == nil, s.LastCheckpointErr() == nil)
+
+	info, err := os.Stat(filepath.Join(dir, "wal"))
+	AllTrue(t, err == nil, info.Size() == 0)
+	AllTrue(t, s.Close() == nil)
+
+	s, err = nset.OpenStore[uint32](dir, 0)
+	AllTrue(t, err == nil, s.Copy().IsEq(nset.FromSlice([]uint32{1, 2, 3, 4})))
+	AllTrue(t, s.Close() == nil)
+}
@@ -0,0 +1,152 @@
+package nset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var _ flag.Value = &FlagValue[uint8]{}
+
+//EncodeString returns the compressed format of the set (see WriteCompressed) as URL-safe base64 without padding,
+//so it can be used in URLs, query strings and config files without escaping
+func (n *NSet[T]) EncodeString() string {
+	return base64.RawURLEncoding.EncodeToString(n.compressedBytes())
+}
+
+//DecodeString replaces the contents and bucket layout of the set with the ones encoded in s by EncodeString
+func (n *NSet[T]) DecodeString(s string) error {
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBinaryData, err)
+	}
+
+	return n.ReadCompressed(bytes.NewReader(data))
+}
+
+//EncodeHexString is like EncodeString but uses lowercase hex, which is longer but safe in even more places
+func (n *NSet[T]) EncodeHexString() string {
+	return hex.EncodeToString(n.compressedBytes())
+}
+
+//DecodeHexString replaces the contents and bucket layout of the set with the ones encoded in s by EncodeHexString
+func (n *NSet[T]) DecodeHexString(s string) error {
+
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBinaryData, err)
+	}
+
+	return n.ReadCompressed(bytes.NewReader(data))
+}
+
+func (n *NSet[T]) compressedBytes() []byte {
+
+	buf := &bytes.Buffer{}
+
+	//Writing to a bytes.Buffer never fails
+	n.WriteCompressed(buf)
+	return buf.Bytes()
+}
+
+//FlagValue is a flag.Value that adds to NSet the elements given on the command line, either as a list of values and
+//inclusive ranges like '1,2,5-100', or as a set encoded with EncodeString. Giving the flag more than once adds all the elements.
+//
+//	ids := nset.NewNSet[uint32]()
+//	flag.Var(nset.NewFlagValue(ids), "ids", "IDs to process, like 1,2,5-100")
+type FlagValue[T IntsIf] struct {
+	NSet *NSet[T]
+}
+
+//NewFlagValue returns a FlagValue that adds to n
+func NewFlagValue[T IntsIf](n *NSet[T]) *FlagValue[T] {
+	return &FlagValue[T]{NSet: n}
+}
+
+//String returns the elements of the set as a list of values and ranges, like '1,2,5-100'
+func (f *FlagValue[T]) String() string {
+
+	//The flag package calls String on a zero value FlagValue to find the default value
+	if f == nil || f.NSet == nil {
+		return ""
+	}
+
+	b := strings.Builder{}
+	f.NSet.IntervalsIter(func(start, end T) bool {
+
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(strconv.FormatUint(uint64(start), 10))
+		if end != start {
+			b.WriteByte('-')
+			b.WriteString(strconv.FormatUint(uint64(end), 10))
+		}
+
+		return true
+	})
+
+	return b.String()
+}
+
+//Set adds the elements in s to the set. s is either a list of values and inclusive ranges like '1,2,5-100',
+//or a set encoded with EncodeString
+func (f *FlagValue[T]) Set(s string) error {
+
+	if f.NSet == nil {
+		f.NSet = NewNSet[T]()
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	//Encoded sets start with the base64 of the compressed format magic, so never with a digit
+	if s[0] < '0' || s[0] > '9' {
+
+		decoded := &NSet[T]{}
+		if err := decoded.DecodeString(s); err != nil {
+			return err
+		}
+
+		f.NSet.Union(decoded)
+		return nil
+	}
+
+	typeBits := int(typeBitsOf[T]())
+	intervals := make([][2]T, 0)
+	for _, part := range strings.Split(s, ",") {
+
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		start, err := strconv.ParseUint(strings.TrimSpace(lo), 10, typeBits)
+		if err != nil {
+			return fmt.Errorf("nset: invalid value %q: %w", part, err)
+		}
+
+		end := start
+		if isRange {
+
+			end, err = strconv.ParseUint(strings.TrimSpace(hi), 10, typeBits)
+			if err != nil {
+				return fmt.Errorf("nset: invalid range %q: %w", part, err)
+			}
+
+			if end < start {
+				return fmt.Errorf("nset: invalid range %q: end is smaller than start", part)
+			}
+		}
+
+		intervals = append(intervals, [2]T{T(start), T(end)})
+	}
+
+	f.NSet.Union(FromIntervals(intervals))
+	return nil
+}
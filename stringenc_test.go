@@ -0,0 +1,61 @@
+package nset_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"math"
+	"net/url"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestNSetEncodeString(t *testing.T) {
+
+	n1 := nset.NewNSet[uint32](nset.WithBucketIndexingBits(4))
+	n1.AddMany(1, 2, 3, 1000, math.MaxUint32)
+	n1.Union(nset.FromRange[uint32](50_000, 60_000))
+
+	s := n1.EncodeString()
+	IsEq(t, s, url.QueryEscape(s))
+
+	n2 := nset.FromSlice([]uint32{7})
+	AllTrue(t, n2.DecodeString(s) == nil, n2.IsEq(n1), !n2.Contains(7))
+	IsEq(t, uint8(4), n2.BucketIndexingBits())
+
+	h := n1.EncodeHexString()
+	n3 := nset.NewNSet[uint32]()
+	AllTrue(t, n3.DecodeHexString(h) == nil, n3.IsEq(n1))
+
+	AllTrue(t, errors.Is(n3.DecodeString("not base64!"), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(n3.DecodeString(s[:len(s)-4]), nset.ErrInvalidBinaryData))
+	AllTrue(t, errors.Is(n3.DecodeHexString("zz"), nset.ErrInvalidBinaryData), n3.IsEq(n1))
+	AllTrue(t, errors.Is(nset.NewNSet[uint16]().DecodeString(s), nset.ErrInvalidBinaryData))
+}
+
+func TestFlagValue(t *testing.T) {
+
+	encoded := nset.FromSlice([]uint16{500, 600}).EncodeString()
+
+	ids := nset.NewNSet[uint16]()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(nset.NewFlagValue(ids), "ids", "IDs")
+
+	AllTrue(t, fs.Parse([]string{"--ids=1,2,5-100", "--ids", " 200 - 202 ,7", "--ids=" + encoded}) == nil)
+	AllTrue(t, ids.IsEq(nset.FromIntervals([][2]uint16{{1, 2}, {5, 100}, {200, 202}, {500, 500}, {600, 600}})))
+	IsEq(t, "1-2,5-100,200-202,500,600", nset.NewFlagValue(ids).String())
+
+	//Invalid values
+	for _, s := range []string{"1,x", "1-", "5-3", "70000", "1,,2", "-5", "Tnot-valid"} {
+		fs = flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(nset.NewFlagValue(nset.NewNSet[uint16]()), "ids", "IDs")
+		AllTrue(t, fs.Parse([]string{"--ids=" + s}) != nil)
+	}
+
+	//Zero value
+	var v nset.FlagValue[uint8]
+	IsEq(t, "", v.String())
+	AllTrue(t, v.Set("0-3") == nil, v.NSet.Len() == 4)
+}
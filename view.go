@@ -0,0 +1,141 @@
+package nset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+//View is a read-only set that uses the binary format of an NSet (see MarshalBinary) in place, without decoding it.
+//
+//On unix systems OpenView memory maps the file, so the storage units of the set are the pages of the file and are
+//loaded by the OS as they are used. Many processes opening the same file share the same memory.
+type View[T IntsIf] struct {
+	set NSet[T]
+	//mapped is the memory mapped file, if any. It is released by Close
+	mapped []byte
+}
+
+//nativeIsLittleEndian is true when storage units in memory have the same byte order as the binary format
+var nativeIsLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+//OpenView opens a file written with MarshalBinary as a read-only set. The file must not be changed while the view is open.
+//Close must be called once the view is no longer needed.
+func OpenView[T IntsIf](path string) (*View[T], error) {
+
+	data, mapped, err := openViewData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &View[T]{mapped: mapped}
+	if err := v.set.initFromBinaryInPlace(data); err != nil {
+		v.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+//initFromBinaryInPlace is like UnmarshalBinary, but the storage units of the buckets use data directly when possible.
+//The set must not be changed after this.
+func (n *NSet[T]) initFromBinaryInPlace(data []byte) error {
+
+	bucketIndexingBits, err := parseBinaryHeader[T](data)
+	if err != nil {
+		return err
+	}
+
+	n.initLayout(bucketIndexingBits)
+
+	offset := binaryStorageUnitsOffset(len(n.Buckets))
+	for i := 0; i < len(n.Buckets); i++ {
+
+		b := &n.Buckets[i]
+		b.StorageUnitCount = binary.LittleEndian.Uint32(data[binaryHeaderSize+i*4:])
+		n.StorageUnitCount += b.StorageUnitCount
+
+		b.Data = storageUnitsInPlace(data[offset : offset+int(b.StorageUnitCount)*8])
+		offset += int(b.StorageUnitCount) * 8
+	}
+
+	return nil
+}
+
+//storageUnitsInPlace returns the little endian storage units in data. The returned slice uses the memory of data if
+//the byte order and alignment allow it, otherwise the storage units are copied
+func storageUnitsInPlace(data []byte) []StorageType {
+
+	count := len(data) / 8
+	if count == 0 {
+		return make([]StorageType, 0)
+	}
+
+	if nativeIsLittleEndian && uintptr(unsafe.Pointer(&data[0]))%unsafe.Alignof(StorageType(0)) == 0 {
+		return unsafe.Slice((*StorageType)(unsafe.Pointer(&data[0])), count)
+	}
+
+	storageUnits := make([]StorageType, count)
+	for i := 0; i < count; i++ {
+		storageUnits[i] = StorageType(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+
+	return storageUnits
+}
+
+//Close releases the file used by the view. The view must not be used after this
+func (v *View[T]) Close() error {
+
+	v.set = NSet[T]{}
+	if v.mapped == nil {
+		return nil
+	}
+
+	mapped := v.mapped
+	v.mapped = nil
+	return unmapViewData(mapped)
+}
+
+func (v *View[T]) Contains(x T) bool {
+	return v.set.Contains(x)
+}
+
+func (v *View[T]) Len() int {
+	return v.set.Len()
+}
+
+//Rank returns the number of elements in the view that are smaller than or equal to x
+func (v *View[T]) Rank(x T) int {
+	return v.set.Rank(x)
+}
+
+//ForEach calls f on each element of the view in ascending order, and stops early if f returns false
+func (v *View[T]) ForEach(f func(x T) bool) {
+	v.set.ForEach(f)
+}
+
+//BucketIndexingBits returns the bucket indexing bits of the set the view was written from
+func (v *View[T]) BucketIndexingBits() uint8 {
+	return v.set.BucketIndexingBits()
+}
+
+func (v *View[T]) HasIntersection(otherSet *NSet[T]) bool {
+	return v.set.HasIntersection(otherSet)
+}
+
+func (v *View[T]) IntersectionLen(otherSet *NSet[T]) int {
+	return v.set.IntersectionLen(otherSet)
+}
+
+//GetIntersection returns a new (heap allocated) set with the elements that are in both the view and otherSet
+func (v *View[T]) GetIntersection(otherSet *NSet[T]) *NSet[T] {
+	return v.set.GetIntersection(otherSet)
+}
+
+//Copy returns a normal (heap allocated) set with the same elements and layout as the view
+func (v *View[T]) Copy() *NSet[T] {
+	return v.set.Copy()
+}
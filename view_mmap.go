@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package nset
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+//openViewData memory maps the file at path as read-only. mapped is the memory that must be released with unmapViewData
+func openViewData(path string) (data []byte, mapped []byte, err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := info.Size()
+	if size < binaryHeaderSize {
+		return nil, nil, fmt.Errorf("%s: %w: file is too short", path, ErrInvalidBinaryData)
+	}
+
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("%s: file is too big to memory map", path)
+	}
+
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: memory mapping file: %w", path, err)
+	}
+
+	return data, data, nil
+}
+
+func unmapViewData(mapped []byte) error {
+	return syscall.Munmap(mapped)
+}
@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package nset
+
+import "os"
+
+//openViewData reads the whole file, as memory mapping isn't supported on this system
+func openViewData(path string) (data []byte, mapped []byte, err error) {
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, nil, nil
+}
+
+func unmapViewData(mapped []byte) error {
+	return nil
+}
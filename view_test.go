@@ -0,0 +1,88 @@
+package nset_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloeys/nset"
+)
+
+func TestView(t *testing.T) {
+
+	n1 := nset.NewNSet[uint32]()
+	n1.AddMany(0, 1, 63, 64, 1000, 10_000_000, math.MaxUint32)
+	n1.Union(nset.FromRange[uint32](1<<30, 1<<30+5000))
+
+	data, err := n1.MarshalBinary()
+	AllTrue(t, err == nil)
+
+	path := filepath.Join(t.TempDir(), "set.nset")
+	AllTrue(t, os.WriteFile(path, data, 0o644) == nil)
+
+	v, err := nset.OpenView[uint32](path)
+	AllTrue(t, err == nil)
+	defer v.Close()
+
+	IsEq(t, n1.Len(), v.Len())
+	IsEq(t, n1.BucketIndexingBits(), v.BucketIndexingBits())
+	AllTrue(t, v.Contains(0), v.Contains(1<<30+5000), v.Contains(math.MaxUint32), !v.Contains(2), !v.Contains(1<<30+5001))
+	AllTrue(t, v.Copy().IsEq(n1))
+
+	IsEq(t, 0, v.Rank(1<<30-1)-v.Rank(10_000_000))
+	IsEq(t, n1.Len(), v.Rank(math.MaxUint32))
+	IsEq(t, 4, v.Rank(999))
+
+	elements := make([]uint32, 0)
+	v.ForEach(func(x uint32) bool {
+		elements = append(elements, x)
+		return true
+	})
+	IsEq(t, n1.Len(), len(elements))
+	IsEq(t, uint32(math.MaxUint32), elements[len(elements)-1])
+
+	//Intersections with heap sets, including ones with a different layout
+	for _, bits := range []uint8{nset.BucketIndexingBits, 3} {
+
+		other := nset.NewNSet[uint32](nset.WithBucketIndexingBits(bits))
+		other.AddMany(1, 2, 1000, 1<<30+100)
+		AllTrue(t, v.HasIntersection(other))
+		IsEq(t, 3, v.IntersectionLen(other))
+
+		intersection := v.GetIntersection(other)
+		AllTrue(t, intersection.IsEq(nset.FromSlice([]uint32{1, 1000, 1<<30 + 100})))
+
+		//The intersection doesn't use the memory of the view
+		intersection.Add(5)
+		AllTrue(t, !v.Contains(5))
+	}
+
+	AllTrue(t, v.Close() == nil)
+}
+
+func TestViewInvalidFile(t *testing.T) {
+
+	dir := t.TempDir()
+
+	_, err := nset.OpenView[uint32](filepath.Join(dir, "missing"))
+	AllTrue(t, errors.Is(err, os.ErrNotExist))
+
+	empty := filepath.Join(dir, "empty")
+	AllTrue(t, os.WriteFile(empty, nil, 0o644) == nil)
+	_, err = nset.OpenView[uint32](empty)
+	AllTrue(t, errors.Is(err, nset.ErrInvalidBinaryData))
+
+	//Wrong element type
+	data, err := nset.FromSlice([]uint16{1, 2, 3}).MarshalBinary()
+	AllTrue(t, err == nil)
+
+	wrongType := filepath.Join(dir, "wrong-type")
+	AllTrue(t, os.WriteFile(wrongType, data, 0o644) == nil)
+	_, err = nset.OpenView[uint32](wrongType)
+	AllTrue(t, errors.Is(err, nset.ErrInvalidBinaryData))
+
+	v, err := nset.OpenView[uint16](wrongType)
+	AllTrue(t, err == nil, v.Contains(2), v.Len() == 3, v.Close() == nil)
+}